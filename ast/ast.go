@@ -8,7 +8,17 @@ import (
 
 type Node interface {
 	TokenLiteral() string
-	String() string //为了方便调试使用，增加了String()方法
+	String() string      //为了方便调试使用，增加了String()方法
+	Pos() token.Position //节点第一个字符的位置
+	End() token.Position //节点最后一个字符之后的位置
+}
+
+// endOf 返回节点的结束位置，节点为空时（通常出现在语法分析出错时）返回fallback
+func endOf(node Node, fallback token.Position) token.Position {
+	if node == nil {
+		return fallback
+	}
+	return node.End()
 }
 
 type Statement interface {
@@ -32,6 +42,18 @@ func (p *Program) TokenLiteral() string { //这个方法只是在debug 的时候
 		return ""
 	}
 }
+func (p *Program) Pos() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+	return token.Position{}
+}
+func (p *Program) End() token.Position {
+	if n := len(p.Statements); n > 0 {
+		return p.Statements[n-1].End()
+	}
+	return token.Position{}
+}
 
 func (p *Program) String() string {
 	var out bytes.Buffer
@@ -53,6 +75,16 @@ func (ls *LetStatement) statementNode() {}
 func (ls *LetStatement) TokenLiteral() string {
 	return ls.Token.Literal
 }
+func (ls *LetStatement) Pos() token.Position { return ls.Token.Pos }
+func (ls *LetStatement) End() token.Position {
+	if ls.Value != nil {
+		return ls.Value.End()
+	}
+	if ls.Name != nil {
+		return ls.Name.End()
+	}
+	return ls.Token.End
+}
 func (ls *LetStatement) String() string {
 	var out bytes.Buffer
 	out.WriteString(ls.TokenLiteral() + " ")
@@ -67,6 +99,41 @@ func (ls *LetStatement) String() string {
 	return out.String()
 }
 
+// ConstStatement 与LetStatement类似，但是绑定之后不能再被赋值，也不能在同一个作用域中被重新声明
+type ConstStatement struct {
+	Token token.Token
+	Name  *Identifier
+	Value Expression
+}
+
+func (cs *ConstStatement) statementNode() {}
+func (cs *ConstStatement) TokenLiteral() string {
+	return cs.Token.Literal
+}
+func (cs *ConstStatement) Pos() token.Position { return cs.Token.Pos }
+func (cs *ConstStatement) End() token.Position {
+	if cs.Value != nil {
+		return cs.Value.End()
+	}
+	if cs.Name != nil {
+		return cs.Name.End()
+	}
+	return cs.Token.End
+}
+func (cs *ConstStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString(cs.TokenLiteral() + " ")
+	out.WriteString(cs.Name.String())
+	out.WriteString(" = ")
+
+	if cs.Value != nil {
+		out.WriteString(cs.Value.String())
+	}
+
+	out.WriteString(";")
+	return out.String()
+}
+
 type Identifier struct {
 	Token token.Token
 	Value string
@@ -77,6 +144,8 @@ func (i *Identifier) expressionNode() {}
 func (i *Identifier) TokenLiteral() string {
 	return i.Token.Literal
 }
+func (i *Identifier) Pos() token.Position { return i.Token.Pos }
+func (i *Identifier) End() token.Position { return i.Token.End }
 func (i *Identifier) String() string {
 	return i.Value
 }
@@ -89,6 +158,10 @@ type ReturnStatement struct {
 func (rs *ReturnStatement) TokenLiteral() string {
 	return rs.Token.Literal
 }
+func (rs *ReturnStatement) Pos() token.Position { return rs.Token.Pos }
+func (rs *ReturnStatement) End() token.Position {
+	return endOf(rs.ReturnValue, rs.Token.End)
+}
 func (rs *ReturnStatement) statementNode() {}
 func (rs *ReturnStatement) String() string {
 	var out bytes.Buffer
@@ -111,6 +184,10 @@ type ExpressionStatement struct { //由于monkey语言中可能会出现a+4这
 func (es *ExpressionStatement) TokenLiteral() string {
 	return es.Token.Literal
 }
+func (es *ExpressionStatement) Pos() token.Position { return es.Token.Pos }
+func (es *ExpressionStatement) End() token.Position {
+	return endOf(es.Expression, es.Token.End)
+}
 
 func (es *ExpressionStatement) statementNode() {}
 func (es *ExpressionStatement) String() string {
@@ -130,10 +207,42 @@ func (il *IntegerLiteral) expressionNode() {
 func (il *IntegerLiteral) TokenLiteral() string {
 	return il.Token.Literal
 }
+func (il *IntegerLiteral) Pos() token.Position { return il.Token.Pos }
+func (il *IntegerLiteral) End() token.Position { return il.Token.End }
 func (il *IntegerLiteral) String() string {
 	return il.Token.Literal
 }
 
+type FloatLiteral struct {
+	Token token.Token
+	Value float64
+}
+
+func (fl *FloatLiteral) expressionNode() {}
+func (fl *FloatLiteral) TokenLiteral() string {
+	return fl.Token.Literal
+}
+func (fl *FloatLiteral) Pos() token.Position { return fl.Token.Pos }
+func (fl *FloatLiteral) End() token.Position { return fl.Token.End }
+func (fl *FloatLiteral) String() string {
+	return fl.Token.Literal
+}
+
+type StringLiteral struct {
+	Token token.Token //Literal中保存的是已经处理过转义序列的字符串内容
+	Value string
+}
+
+func (sl *StringLiteral) expressionNode() {}
+func (sl *StringLiteral) TokenLiteral() string {
+	return sl.Token.Literal
+}
+func (sl *StringLiteral) Pos() token.Position { return sl.Token.Pos }
+func (sl *StringLiteral) End() token.Position { return sl.Token.End }
+func (sl *StringLiteral) String() string {
+	return sl.Token.Literal
+}
+
 type PrefixExpression struct {
 	Token    token.Token
 	Operator string
@@ -144,6 +253,10 @@ func (pe *PrefixExpression) expressionNode() {}
 func (pe *PrefixExpression) TokenLiteral() string {
 	return pe.Token.Literal
 }
+func (pe *PrefixExpression) Pos() token.Position { return pe.Token.Pos }
+func (pe *PrefixExpression) End() token.Position {
+	return endOf(pe.Right, pe.Token.End)
+}
 
 func (pe *PrefixExpression) String() string {
 	var out bytes.Buffer
@@ -167,6 +280,15 @@ func (ie *InfixExpression) expressionNode() {
 func (ie *InfixExpression) TokenLiteral() string {
 	return ie.Token.Literal
 }
+func (ie *InfixExpression) Pos() token.Position {
+	if ie.Left != nil {
+		return ie.Left.Pos()
+	}
+	return ie.Token.Pos
+}
+func (ie *InfixExpression) End() token.Position {
+	return endOf(ie.Right, ie.Token.End)
+}
 func (ie *InfixExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
@@ -178,6 +300,38 @@ func (ie *InfixExpression) String() string {
 	return out.String()
 }
 
+// AssignExpression 表示对已有绑定或者数组、哈希表元素的赋值，Operator为=、+=、-=、*=或/=
+type AssignExpression struct {
+	Token    token.Token //赋值运算符词法单元
+	Target   Expression  //*Identifier或者*IndexExpression
+	Operator string
+	Value    Expression
+}
+
+func (ae *AssignExpression) expressionNode() {}
+func (ae *AssignExpression) TokenLiteral() string {
+	return ae.Token.Literal
+}
+func (ae *AssignExpression) Pos() token.Position {
+	if ae.Target != nil {
+		return ae.Target.Pos()
+	}
+	return ae.Token.Pos
+}
+func (ae *AssignExpression) End() token.Position {
+	return endOf(ae.Value, ae.Token.End)
+}
+func (ae *AssignExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("(")
+	out.WriteString(ae.Target.String())
+	out.WriteString(" " + ae.Operator + " ")
+	out.WriteString(ae.Value.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+
 type Boolean struct {
 	Token token.Token
 	Value bool
@@ -189,6 +343,8 @@ func (b *Boolean) expressionNode() {
 func (b *Boolean) TokenLiteral() string {
 	return b.Token.Literal
 }
+func (b *Boolean) Pos() token.Position { return b.Token.Pos }
+func (b *Boolean) End() token.Position { return b.Token.End }
 
 func (b *Boolean) String() string {
 	return b.Token.Literal
@@ -206,6 +362,16 @@ func (ie *IfExpression) expressionNode() {
 func (ie *IfExpression) TokenLiteral() string {
 	return ie.Token.Literal
 }
+func (ie *IfExpression) Pos() token.Position { return ie.Token.Pos }
+func (ie *IfExpression) End() token.Position {
+	if ie.Alternative != nil {
+		return ie.Alternative.End()
+	}
+	if ie.Consequence != nil {
+		return ie.Consequence.End()
+	}
+	return ie.Token.End
+}
 func (ie *IfExpression) String() string {
 	var out bytes.Buffer
 
@@ -221,9 +387,186 @@ func (ie *IfExpression) String() string {
 	return out.String()
 }
 
+// TernaryExpression 表示cond ? a : b，只会对Consequence和Alternative中的一个求值
+type TernaryExpression struct {
+	Token       token.Token //'?'词法单元
+	Condition   Expression
+	Consequence Expression
+	Alternative Expression
+}
+
+func (te *TernaryExpression) expressionNode() {}
+func (te *TernaryExpression) TokenLiteral() string {
+	return te.Token.Literal
+}
+func (te *TernaryExpression) Pos() token.Position {
+	if te.Condition != nil {
+		return te.Condition.Pos()
+	}
+	return te.Token.Pos
+}
+func (te *TernaryExpression) End() token.Position {
+	return endOf(te.Alternative, te.Token.End)
+}
+func (te *TernaryExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("(")
+	out.WriteString(te.Condition.String())
+	out.WriteString(" ? ")
+	out.WriteString(te.Consequence.String())
+	out.WriteString(" : ")
+	out.WriteString(te.Alternative.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+
+// MatchExpression 表示match (subject) { pattern if guard => body, ... }，按顺序选择第一个匹配的分支求值
+type MatchExpression struct {
+	Token   token.Token //match词法单元
+	Subject Expression
+	Arms    []*MatchArm
+	Rbrace  token.Token //'}'词法单元
+}
+
+// MatchArm 是match表达式中的一个分支。Pattern可以是字面量、标识符（_表示通配）以及由模式组成的数组和哈希表，
+// Guard为nil表示没有if条件。Body是一个表达式或者*BlockStatement：=>之后紧跟{时总是解析为代码块，
+// 因此分支的值是哈希表字面量时需要写成_ => ({"k": 1})
+type MatchArm struct {
+	Pattern Expression
+	Guard   Expression
+	Body    Node
+}
+
+func (ma *MatchArm) String() string {
+	var out bytes.Buffer
+	out.WriteString(ma.Pattern.String())
+	if ma.Guard != nil {
+		out.WriteString(" if ")
+		out.WriteString(ma.Guard.String())
+	}
+	out.WriteString(" => ")
+	out.WriteString(ma.Body.String())
+	return out.String()
+}
+
+func (me *MatchExpression) expressionNode() {}
+func (me *MatchExpression) TokenLiteral() string {
+	return me.Token.Literal
+}
+func (me *MatchExpression) Pos() token.Position { return me.Token.Pos }
+func (me *MatchExpression) End() token.Position {
+	if me.Rbrace.End.IsValid() {
+		return me.Rbrace.End
+	}
+	return me.Token.End
+}
+func (me *MatchExpression) String() string {
+	var out bytes.Buffer
+
+	arms := []string{}
+	for _, arm := range me.Arms {
+		arms = append(arms, arm.String())
+	}
+
+	out.WriteString("match (")
+	out.WriteString(me.Subject.String())
+	out.WriteString(") { ")
+	out.WriteString(strings.Join(arms, ", "))
+	out.WriteString(" }")
+	return out.String()
+}
+
+type WhileExpression struct {
+	Token     token.Token //while词法单元
+	Condition Expression
+	Body      *BlockStatement
+}
+
+func (we *WhileExpression) expressionNode() {}
+func (we *WhileExpression) TokenLiteral() string {
+	return we.Token.Literal
+}
+func (we *WhileExpression) Pos() token.Position { return we.Token.Pos }
+func (we *WhileExpression) End() token.Position {
+	if we.Body != nil {
+		return we.Body.End()
+	}
+	return we.Token.End
+}
+func (we *WhileExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("while")
+	out.WriteString(we.Condition.String())
+	out.WriteString(" ")
+	out.WriteString(we.Body.String())
+	return out.String()
+}
+
+// ForInExpression 表示for (x in iterable) { ... }，每一次迭代都会把当前元素绑定到Variable上
+type ForInExpression struct {
+	Token    token.Token //for词法单元
+	Variable *Identifier
+	Iterable Expression
+	Body     *BlockStatement
+}
+
+func (fe *ForInExpression) expressionNode() {}
+func (fe *ForInExpression) TokenLiteral() string {
+	return fe.Token.Literal
+}
+func (fe *ForInExpression) Pos() token.Position { return fe.Token.Pos }
+func (fe *ForInExpression) End() token.Position {
+	if fe.Body != nil {
+		return fe.Body.End()
+	}
+	return fe.Token.End
+}
+func (fe *ForInExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("for (")
+	out.WriteString(fe.Variable.String())
+	out.WriteString(" in ")
+	out.WriteString(fe.Iterable.String())
+	out.WriteString(") ")
+	out.WriteString(fe.Body.String())
+	return out.String()
+}
+
+type BreakStatement struct {
+	Token token.Token
+}
+
+func (bs *BreakStatement) statementNode() {}
+func (bs *BreakStatement) TokenLiteral() string {
+	return bs.Token.Literal
+}
+func (bs *BreakStatement) Pos() token.Position { return bs.Token.Pos }
+func (bs *BreakStatement) End() token.Position { return bs.Token.End }
+func (bs *BreakStatement) String() string {
+	return bs.TokenLiteral() + ";"
+}
+
+type ContinueStatement struct {
+	Token token.Token
+}
+
+func (cs *ContinueStatement) statementNode() {}
+func (cs *ContinueStatement) TokenLiteral() string {
+	return cs.Token.Literal
+}
+func (cs *ContinueStatement) Pos() token.Position { return cs.Token.Pos }
+func (cs *ContinueStatement) End() token.Position { return cs.Token.End }
+func (cs *ContinueStatement) String() string {
+	return cs.TokenLiteral() + ";"
+}
+
 type BlockStatement struct {
-	Token      token.Token
+	Token      token.Token //'{'词法单元
 	Statements []Statement
+	Rbrace     token.Token //'}'词法单元
 }
 
 func (bs *BlockStatement) statementNode() {
@@ -231,6 +574,16 @@ func (bs *BlockStatement) statementNode() {
 func (bs *BlockStatement) TokenLiteral() string {
 	return bs.Token.Literal
 }
+func (bs *BlockStatement) Pos() token.Position { return bs.Token.Pos }
+func (bs *BlockStatement) End() token.Position {
+	if bs.Rbrace.End.IsValid() {
+		return bs.Rbrace.End
+	}
+	if n := len(bs.Statements); n > 0 && bs.Statements[n-1] != nil {
+		return bs.Statements[n-1].End()
+	}
+	return bs.Token.End
+}
 func (bs *BlockStatement) String() string {
 	var out bytes.Buffer
 	for _, s := range bs.Statements {
@@ -251,6 +604,13 @@ func (fl *FunctionLiteral) expressionNode() {
 func (fl *FunctionLiteral) TokenLiteral() string {
 	return fl.Token.Literal
 }
+func (fl *FunctionLiteral) Pos() token.Position { return fl.Token.Pos }
+func (fl *FunctionLiteral) End() token.Position {
+	if fl.Body != nil {
+		return fl.Body.End()
+	}
+	return fl.Token.End
+}
 
 func (fl *FunctionLiteral) String() string {
 	var out bytes.Buffer
@@ -273,6 +633,7 @@ type CallExpression struct { //调用函数的结构体
 	Token     token.Token
 	Function  Expression
 	Arguments []Expression
+	Rparen    token.Token //')'词法单元
 }
 
 func (ce *CallExpression) expressionNode() {
@@ -282,6 +643,18 @@ func (ce *CallExpression) expressionNode() {
 func (ce *CallExpression) TokenLiteral() string {
 	return ce.Token.Literal
 }
+func (ce *CallExpression) Pos() token.Position {
+	if ce.Function != nil {
+		return ce.Function.Pos()
+	}
+	return ce.Token.Pos
+}
+func (ce *CallExpression) End() token.Position {
+	if ce.Rparen.End.IsValid() {
+		return ce.Rparen.End
+	}
+	return ce.Token.End
+}
 
 func (ce *CallExpression) String() string {
 	var out bytes.Buffer
@@ -298,3 +671,106 @@ func (ce *CallExpression) String() string {
 
 	return out.String()
 }
+
+type ArrayLiteral struct {
+	Token    token.Token //'['词法单元
+	Elements []Expression
+	Rbracket token.Token //']'词法单元
+}
+
+func (al *ArrayLiteral) expressionNode() {}
+func (al *ArrayLiteral) TokenLiteral() string {
+	return al.Token.Literal
+}
+func (al *ArrayLiteral) Pos() token.Position { return al.Token.Pos }
+func (al *ArrayLiteral) End() token.Position {
+	if al.Rbracket.End.IsValid() {
+		return al.Rbracket.End
+	}
+	return al.Token.End
+}
+func (al *ArrayLiteral) String() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, el := range al.Elements {
+		elements = append(elements, el.String())
+	}
+
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+
+	return out.String()
+}
+
+type IndexExpression struct { //索引表达式，例如array[1]
+	Token    token.Token //'['词法单元
+	Left     Expression
+	Index    Expression
+	Rbracket token.Token //']'词法单元
+}
+
+func (ie *IndexExpression) expressionNode() {}
+func (ie *IndexExpression) TokenLiteral() string {
+	return ie.Token.Literal
+}
+func (ie *IndexExpression) Pos() token.Position {
+	if ie.Left != nil {
+		return ie.Left.Pos()
+	}
+	return ie.Token.Pos
+}
+func (ie *IndexExpression) End() token.Position {
+	if ie.Rbracket.End.IsValid() {
+		return ie.Rbracket.End
+	}
+	return endOf(ie.Index, ie.Token.End)
+}
+func (ie *IndexExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(ie.Left.String())
+	out.WriteString("[")
+	out.WriteString(ie.Index.String())
+	out.WriteString("])")
+
+	return out.String()
+}
+
+// HashLiteral 是哈希表字面量。Pairs是map，遍历的顺序是随机的，
+// 需要按照源代码中的顺序求值或者输出时应该遍历Keys，Keys按顺序保存了Pairs中所有的键
+type HashLiteral struct {
+	Token  token.Token //'{'词法单元
+	Pairs  map[Expression]Expression
+	Keys   []Expression
+	Rbrace token.Token //'}'词法单元
+}
+
+func (hl *HashLiteral) expressionNode() {}
+func (hl *HashLiteral) TokenLiteral() string {
+	return hl.Token.Literal
+}
+func (hl *HashLiteral) Pos() token.Position { return hl.Token.Pos }
+func (hl *HashLiteral) End() token.Position {
+	if hl.Rbrace.End.IsValid() {
+		return hl.Rbrace.End
+	}
+	return hl.Token.End
+}
+
+func (hl *HashLiteral) String() string {
+	var out bytes.Buffer
+
+	pairs := []string{}
+	for _, key := range hl.Keys {
+		pairs = append(pairs, key.String()+":"+hl.Pairs[key].String())
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
@@ -26,3 +26,21 @@ func TestString(t *testing.T) {
 		t.Errorf("program.String() wrong. got=%q", program.String())
 	}
 }
+
+func TestHashLiteralString(t *testing.T) {
+	//手工构造的节点没有位置信息，输出的顺序由Keys决定
+	keys := []Expression{}
+	pairs := map[Expression]Expression{}
+	for _, k := range []string{"z", "a", "m"} {
+		key := &StringLiteral{Token: token.Token{Type: token.STRING, Literal: k}, Value: k}
+		keys = append(keys, key)
+		pairs[key] = &Identifier{Token: token.Token{Type: token.IDENT, Literal: "v"}, Value: "v"}
+	}
+	hash := &HashLiteral{Token: token.Token{Type: token.LBRACE, Literal: "{"}, Pairs: pairs, Keys: keys}
+
+	for i := 0; i < 10; i++ {
+		if hash.String() != "{z:v, a:v, m:v}" {
+			t.Fatalf("hash.String() wrong. got=%q", hash.String())
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package diagnostic
+
+import (
+	"bytes"
+	"fmt"
+	"monkey/token"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type Severity int
+
+const (
+	Error Severity = iota
+	Warning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Error:
+		return "error"
+	case Warning:
+		return "warning"
+	default:
+		return "unknown"
+	}
+}
+
+// Span 表示源代码中的一段区间，End为最后一个字符之后的位置
+type Span struct {
+	Start token.Position
+	End   token.Position
+}
+
+// Diagnostic 是词法分析和语法分析阶段产生的一条诊断信息
+type Diagnostic struct {
+	Severity Severity
+	Span     Span
+	Message  string
+	Hint     string //可选的修复建议
+}
+
+func Errorf(start, end token.Position, format string, a ...interface{}) Diagnostic {
+	return Diagnostic{Severity: Error, Span: Span{Start: start, End: end}, Message: fmt.Sprintf(format, a...)}
+}
+
+// String 返回file:line:col: message形式的单行描述
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s", d.Span.Start, d.Message)
+}
+
+// Render 输出诊断信息以及出错的那一行源代码，并在出错的区间下方用^~~~进行标记，例如：
+//
+//	script.monkey:2:9: error: expected next token to be ), got ; instead
+//	  |
+//	2 | add(1, 2;
+//	  |         ^
+//	  = hint: to match ( at script.monkey:2:4
+func Render(source string, d Diagnostic) string {
+	var out bytes.Buffer
+	start := d.Span.Start
+
+	fmt.Fprintf(&out, "%s: %s: %s\n", start, d.Severity, d.Message)
+
+	line, ok := sourceLine(source, start)
+	if ok {
+		number := strconv.Itoa(start.Line)
+		gutter := strings.Repeat(" ", len(number))
+
+		fmt.Fprintf(&out, "%s |\n", gutter)
+		fmt.Fprintf(&out, "%s | %s\n", number, line.text)
+		fmt.Fprintf(&out, "%s | %s\n", gutter, underline(line, d.Span))
+		if d.Hint != "" {
+			fmt.Fprintf(&out, "%s = hint: %s\n", gutter, d.Hint)
+		}
+	} else if d.Hint != "" {
+		fmt.Fprintf(&out, "  = hint: %s\n", d.Hint)
+	}
+	return out.String()
+}
+
+// RenderAll 依次输出所有诊断信息
+func RenderAll(source string, diagnostics []Diagnostic) string {
+	var out bytes.Buffer
+	for _, d := range diagnostics {
+		out.WriteString(Render(source, d))
+	}
+	return out.String()
+}
+
+type lineInfo struct {
+	text  string
+	start int //该行第一个字节在源代码中的偏移
+}
+
+// sourceLine 根据位置中的字节偏移找到其所在的那一行
+func sourceLine(source string, pos token.Position) (lineInfo, bool) {
+	if !pos.IsValid() || pos.Offset < 0 || pos.Offset > len(source) {
+		return lineInfo{}, false
+	}
+	start := strings.LastIndexByte(source[:pos.Offset], '\n') + 1
+	end := strings.IndexByte(source[pos.Offset:], '\n')
+	if end < 0 {
+		end = len(source)
+	} else {
+		end += pos.Offset
+	}
+	return lineInfo{text: strings.TrimRight(source[start:end], "\r"), start: start}, true
+}
+
+// underline 生成标记行。前缀中的制表符原样保留，其余字符按照显示宽度替换为空格，以保证^与源代码对齐
+func underline(line lineInfo, span Span) string {
+	var out bytes.Buffer
+
+	startCol := span.Start.Offset - line.start
+	if startCol > len(line.text) {
+		startCol = len(line.text)
+	}
+	for _, r := range line.text[:startCol] {
+		if r == '\t' {
+			out.WriteRune('\t')
+		} else {
+			out.WriteString(strings.Repeat(" ", runeWidth(r)))
+		}
+	}
+	out.WriteByte('^')
+
+	endCol := len(line.text) //跨越多行的区间一直标记到行尾
+	if span.End.IsValid() && span.End.Line == span.Start.Line {
+		endCol = span.End.Offset - line.start
+	}
+	if endCol > len(line.text) {
+		endCol = len(line.text)
+	}
+	if endCol > startCol {
+		width := 0
+		for _, r := range line.text[startCol:endCol] {
+			width += runeWidth(r)
+		}
+		out.WriteString(strings.Repeat("~", width-1))
+	}
+	return out.String()
+}
+
+// runeWidth 返回字符在终端中的显示宽度，中日韩文字和全角符号占两列
+func runeWidth(r rune) int {
+	if unicode.In(r, unicode.Han, unicode.Hangul, unicode.Hiragana, unicode.Katakana) ||
+		r >= 0x3000 && r <= 0x303F || r >= 0xFF01 && r <= 0xFF60 || r >= 0xFFE0 && r <= 0xFFE6 {
+		return 2
+	}
+	return 1
+}
@@ -0,0 +1,70 @@
+package diagnostic
+
+import (
+	"monkey/token"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	source := "let x = 1;\nlet y = foobar + 2;\n"
+	start := token.Position{Filename: "test.monkey", Offset: 19, Line: 2, Column: 9}
+	end := token.Position{Filename: "test.monkey", Offset: 25, Line: 2, Column: 15}
+
+	d := Errorf(start, end, "identifier not found: %s", "foobar")
+	d.Hint = "did you mean x?"
+
+	expected := `test.monkey:2:9: error: identifier not found: foobar
+  |
+2 | let y = foobar + 2;
+  |         ^~~~~~
+  = hint: did you mean x?
+`
+	if got := Render(source, d); got != expected {
+		t.Errorf("wrong rendering. expected=\n%s\ngot=\n%s", expected, got)
+	}
+
+	if d.String() != "test.monkey:2:9: identifier not found: foobar" {
+		t.Errorf("wrong String(). got=%q", d.String())
+	}
+}
+
+func TestRenderMultiLineSpan(t *testing.T) {
+	source := "if (x) {\n  1\n"
+	start := token.Position{Offset: 7, Line: 1, Column: 8}
+	end := token.Position{Offset: 12, Line: 3, Column: 1}
+
+	expected := `1:8: warning: block is never closed
+  |
+1 | if (x) {
+  |        ^
+`
+	d := Diagnostic{Severity: Warning, Span: Span{Start: start, End: end}, Message: "block is never closed"}
+	if got := Render(source, d); got != expected {
+		t.Errorf("wrong rendering. expected=\n%s\ngot=\n%s", expected, got)
+	}
+}
+
+func TestRenderWithoutPosition(t *testing.T) {
+	d := Diagnostic{Severity: Error, Message: "something went wrong", Hint: "try again"}
+
+	expected := "-: error: something went wrong\n  = hint: try again\n"
+	if got := Render("", d); got != expected {
+		t.Errorf("wrong rendering. expected=%q, got=%q", expected, got)
+	}
+}
+
+func TestRenderWideCharacters(t *testing.T) {
+	source := `let 名字 = "你好" + 1;`
+	start := token.Position{Offset: 13, Line: 1, Column: 9}
+	end := token.Position{Offset: 25, Line: 1, Column: 17}
+
+	expected := `1:9: error: type mismatch: STRING + INTEGER
+  |
+1 | let 名字 = "你好" + 1;
+  |            ^~~~~~~~~~
+`
+	d := Errorf(start, end, "type mismatch: STRING + INTEGER")
+	if got := Render(source, d); got != expected {
+		t.Errorf("wrong rendering. expected=\n%s\ngot=\n%s", expected, got)
+	}
+}
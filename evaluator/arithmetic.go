@@ -0,0 +1,202 @@
+package evaluator
+
+import (
+	"math"
+	"math/big"
+	"monkey/object"
+)
+
+// maxBigShift 限制提升为BigInteger之后左移的位数，避免一次运算耗尽内存
+const maxBigShift = 1 << 20
+
+// checkedIntegerArithmetic 对+ - * / <<进行求值，ok为false表示结果超出了int64的范围，
+// 调用方需保证除数不为0、移位位数不为负数
+func checkedIntegerArithmetic(operator string, a, b int64) (result int64, ok bool) {
+	switch operator {
+	case "+":
+		result = a + b
+		return result, (a^result)&(b^result) >= 0
+	case "-":
+		result = a - b
+		return result, (a^b)&(a^result) >= 0
+	case "*":
+		if a == 0 || b == 0 {
+			return 0, true
+		}
+		result = a * b
+		if (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+			return result, false
+		}
+		return result, result/b == a
+	case "/":
+		result = a / b
+		return result, !(a == math.MinInt64 && b == -1)
+	case "<<":
+		if b >= 64 { //Go中移出全部位时结果为0，只有0本身不会溢出
+			return 0, a == 0
+		}
+		result = a << uint(b)
+		return result, result>>uint(b) == a
+	}
+	return 0, true
+}
+
+// evalIntegerArithmetic 根据env中的溢出处理方式对两个int64进行算术运算，调用方需保证除数不为0
+func evalIntegerArithmetic(operator string, a, b int64, env *object.Environment) object.Object {
+	result, ok := checkedIntegerArithmetic(operator, a, b)
+	if ok {
+		return &object.Integer{Value: result}
+	}
+	switch env.OverflowMode() {
+	case object.OverflowWrap:
+		return &object.Integer{Value: result}
+	case object.OverflowError:
+		return newError("integer overflow: %d %s %d", a, operator, b)
+	}
+	return evalBigIntegerArithmetic(operator, big.NewInt(a), big.NewInt(b))
+}
+
+func evalBigIntegerInfixExpression(operator string, left object.Object, right object.Object) object.Object {
+	leftVal := toBigInt(left)
+	rightVal := toBigInt(right)
+	switch operator {
+	case "+", "-", "*", "&", "|", "^":
+		return evalBigIntegerArithmetic(operator, leftVal, rightVal)
+	case "/", "%":
+		if rightVal.Sign() == 0 {
+			return newError("division by zero")
+		}
+		return evalBigIntegerArithmetic(operator, leftVal, rightVal)
+	case "<<", ">>":
+		if rightVal.Sign() < 0 {
+			return newError("negative shift count: %s", rightVal)
+		}
+		return evalBigIntegerArithmetic(operator, leftVal, rightVal)
+
+	case "<":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) < 0)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) > 0)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) <= 0)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) >= 0)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) == 0)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) != 0)
+	default:
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+func evalBigIntegerArithmetic(operator string, a, b *big.Int) object.Object {
+	result := new(big.Int)
+	switch operator {
+	case "+":
+		result.Add(a, b)
+	case "-":
+		result.Sub(a, b)
+	case "*":
+		result.Mul(a, b)
+	case "/":
+		result.Quo(a, b) //Quo向零取整，与int64的除法保持一致
+	case "%":
+		result.Rem(a, b) //Rem的符号与被除数一致，与int64的取余保持一致
+	case "&":
+		result.And(a, b)
+	case "|":
+		result.Or(a, b)
+	case "^":
+		result.Xor(a, b)
+	case "<<":
+		if !b.IsInt64() || b.Int64() > maxBigShift {
+			return newError("shift count too large: %s", b)
+		}
+		result.Lsh(a, uint(b.Int64()))
+	case ">>":
+		if !b.IsInt64() { //右移的位数超过int64时结果只取决于符号
+			b = big.NewInt(math.MaxInt64)
+		}
+		result.Rsh(a, uint(b.Int64()))
+	}
+	return normalizeBigInteger(result)
+}
+
+// normalizeBigInteger 当结果重新落回int64范围内时，将其还原为普通的object.Integer
+func normalizeBigInteger(value *big.Int) object.Object {
+	if value.IsInt64() {
+		return &object.Integer{Value: value.Int64()}
+	}
+	return &object.BigInteger{Value: value}
+}
+
+func toBigInt(obj object.Object) *big.Int {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return big.NewInt(obj.Value)
+	case *object.BigInteger:
+		return obj.Value
+	}
+	return nil
+}
+
+func isIntegerLike(obj object.Object) bool {
+	return obj.Type() == object.INTEGER_OBJ || obj.Type() == object.BIG_INT_OBJ
+}
+
+// evalFloatInfixExpression 对浮点数以及浮点数与整数的混合运算求值，与整数一样，除数为0时返回错误而不是Inf
+func evalFloatInfixExpression(operator string, left object.Object, right object.Object) object.Object {
+	leftVal := toFloat(left)
+	rightVal := toFloat(right)
+	switch operator {
+	case "+":
+		return &object.Float{Value: leftVal + rightVal}
+	case "-":
+		return &object.Float{Value: leftVal - rightVal}
+	case "*":
+		return &object.Float{Value: leftVal * rightVal}
+	case "/":
+		if rightVal == 0 {
+			return newError("division by zero")
+		}
+		return &object.Float{Value: leftVal / rightVal}
+	case "%":
+		if rightVal == 0 {
+			return newError("division by zero")
+		}
+		return &object.Float{Value: math.Mod(leftVal, rightVal)} //结果的符号与被除数一致，与整数取余保持一致
+
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+func toFloat(obj object.Object) float64 {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return float64(obj.Value)
+	case *object.BigInteger:
+		f, _ := new(big.Float).SetInt(obj.Value).Float64()
+		return f
+	case *object.Float:
+		return obj.Value
+	}
+	return 0
+}
+
+func isNumeric(obj object.Object) bool {
+	return isIntegerLike(obj) || obj.Type() == object.FLOAT_OBJ
+}
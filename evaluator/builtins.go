@@ -0,0 +1,123 @@
+package evaluator
+
+import (
+	"fmt"
+	"monkey/object"
+	"unicode/utf8"
+)
+
+// builtins 是内置函数表，当环境中找不到同名的用户绑定时才会查询这里
+var builtins = map[string]*object.Builtin{
+	"len":   {Fn: builtinLen},
+	"first": {Fn: builtinFirst},
+	"last":  {Fn: builtinLast},
+	"rest":  {Fn: builtinRest},
+	"push":  {Fn: builtinPush},
+	"puts":  {Fn: builtinPuts},
+	"type":  {Fn: builtinType},
+}
+
+// len 返回字符串的字符（rune）数、数组的元素个数或者哈希表的键值对个数
+func builtinLen(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments: want=1, got=%d", len(args))
+	}
+
+	switch arg := args[0].(type) {
+	case *object.String:
+		return &object.Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
+	case *object.Array:
+		return &object.Integer{Value: int64(len(arg.Elements))}
+	case *object.Hash:
+		return &object.Integer{Value: int64(len(arg.Pairs))}
+	default:
+		return newError("argument to `len` not supported, got %s", args[0].Type())
+	}
+}
+
+// first 返回数组的第一个元素，空数组返回NULL
+func builtinFirst(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments: want=1, got=%d", len(args))
+	}
+	if args[0].Type() != object.ARRAY_OBJ {
+		return newError("argument to `first` must be ARRAY, got %s", args[0].Type())
+	}
+
+	arr := args[0].(*object.Array)
+	if len(arr.Elements) > 0 {
+		return arr.Elements[0]
+	}
+	return NULL
+}
+
+// last 返回数组的最后一个元素，空数组返回NULL
+func builtinLast(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments: want=1, got=%d", len(args))
+	}
+	if args[0].Type() != object.ARRAY_OBJ {
+		return newError("argument to `last` must be ARRAY, got %s", args[0].Type())
+	}
+
+	arr := args[0].(*object.Array)
+	length := len(arr.Elements)
+	if length > 0 {
+		return arr.Elements[length-1]
+	}
+	return NULL
+}
+
+// rest 返回除第一个元素以外的所有元素组成的新数组，空数组返回NULL
+func builtinRest(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments: want=1, got=%d", len(args))
+	}
+	if args[0].Type() != object.ARRAY_OBJ {
+		return newError("argument to `rest` must be ARRAY, got %s", args[0].Type())
+	}
+
+	arr := args[0].(*object.Array)
+	length := len(arr.Elements)
+	if length > 0 {
+		newElements := make([]object.Object, length-1)
+		copy(newElements, arr.Elements[1:length])
+		return &object.Array{Elements: newElements}
+	}
+	return NULL
+}
+
+// push 返回在末尾追加了新元素的新数组，原数组保持不变
+func builtinPush(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments: want=2, got=%d", len(args))
+	}
+	if args[0].Type() != object.ARRAY_OBJ {
+		return newError("argument to `push` must be ARRAY, got %s", args[0].Type())
+	}
+
+	arr := args[0].(*object.Array)
+	length := len(arr.Elements)
+
+	newElements := make([]object.Object, length+1)
+	copy(newElements, arr.Elements)
+	newElements[length] = args[1]
+
+	return &object.Array{Elements: newElements}
+}
+
+// puts 将每个参数单独输出一行
+func builtinPuts(args ...object.Object) object.Object {
+	for _, arg := range args {
+		fmt.Println(arg.Inspect())
+	}
+	return NULL
+}
+
+// type 以字符串的形式返回参数的类型名，例如"INTEGER"
+func builtinType(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments: want=1, got=%d", len(args))
+	}
+	return &object.String{Value: string(args[0].Type())}
+}
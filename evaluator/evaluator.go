@@ -1,6 +1,9 @@
 package evaluator
 
 import (
+	"fmt"
+	"math"
+	"math/big"
 	"monkey/ast"
 	"monkey/object"
 )
@@ -9,45 +12,486 @@ var (
 	NULL  = &object.Null{}
 	TRUE  = &object.Boolean{Value: true}
 	FALSE = &object.Boolean{Value: false}
+
+	breakSignal    = &object.Break{}
+	continueSignal = &object.Continue{}
 )
 
-func Eval(node ast.Node) object.Object {
+func Eval(node ast.Node, env *object.Environment) object.Object {
+	result := evalNode(node, env)
+	if errObj, ok := result.(*object.Error); ok && !errObj.Pos.IsValid() { //错误由当前节点产生时，记录当前节点的位置
+		errObj.Pos = node.Pos()
+	}
+	return result
+}
+
+func evalNode(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 	case *ast.Program:
-		return evalStatements(node.Statements)
+		return evalProgram(node, env)
 
 	case *ast.ExpressionStatement:
-		return Eval(node.Expression)
+		return Eval(node.Expression, env)
 	case *ast.IntegerLiteral:
 		return &object.Integer{Value: node.Value}
+	case *ast.FloatLiteral:
+		return &object.Float{Value: node.Value}
 	case *ast.Boolean:
 		return nativeBoolToBooleanObject(node.Value)
+	case *ast.StringLiteral:
+		return &object.String{Value: node.Value}
 	case *ast.PrefixExpression:
-		right := Eval(node.Right)
-		return evalPrefixExpression(node.Operator, right)
+		right := Eval(node.Right, env)
+		if isError(right) || isControlSignal(right) {
+			return right
+		}
+		return evalPrefixExpression(node.Operator, right, env)
 	case *ast.InfixExpression:
-		left := Eval(node.Left)
-		right := Eval(node.Right)
-		return evalInfixExpression(node.Operator, left, right)
+		if node.Operator == "&&" || node.Operator == "||" {
+			return evalLogicalExpression(node, env)
+		}
+		left := Eval(node.Left, env)
+		if isError(left) || isControlSignal(left) {
+			return left
+		}
+		right := Eval(node.Right, env)
+		if isError(right) || isControlSignal(right) {
+			return right
+		}
+		return evalInfixExpression(node.Operator, left, right, env)
 	case *ast.BlockStatement:
-		return evalStatements(node.Statements)
+		return evalBlockStatement(node, env)
 	case *ast.IfExpression:
-		return evalIfExpression(node)
+		return evalIfExpression(node, env)
+	case *ast.TernaryExpression:
+		return evalTernaryExpression(node, env)
+	case *ast.MatchExpression:
+		return evalMatchExpression(node, env)
+	case *ast.WhileExpression:
+		return evalWhileExpression(node, env)
+	case *ast.ForInExpression:
+		return evalForInExpression(node, env)
+	case *ast.BreakStatement:
+		return breakSignal
+	case *ast.ContinueStatement:
+		return continueSignal
+	case *ast.ReturnStatement:
+		val := Eval(node.ReturnValue, env)
+		if isError(val) || isControlSignal(val) {
+			return val
+		}
+		return &object.ReturnValue{Value: val}
+	case *ast.LetStatement:
+		if env.IsLocalConst(node.Name.Value) {
+			return newError("cannot redeclare constant %s", node.Name.Value)
+		}
+		val := Eval(node.Value, env)
+		if isError(val) || isControlSignal(val) {
+			return val
+		}
+		env.Set(node.Name.Value, val)
+	case *ast.ConstStatement:
+		if env.IsLocalConst(node.Name.Value) {
+			return newError("cannot redeclare constant %s", node.Name.Value)
+		}
+		val := Eval(node.Value, env)
+		if isError(val) || isControlSignal(val) {
+			return val
+		}
+		env.SetConst(node.Name.Value, val)
+	case *ast.Identifier:
+		return evalIdentifier(node, env)
+	case *ast.FunctionLiteral:
+		return &object.Function{Parameters: node.Parameters, Body: node.Body, Env: env}
+	case *ast.CallExpression:
+		function := Eval(node.Function, env)
+		if isError(function) || isControlSignal(function) {
+			return function
+		}
+		args := evalExpressions(node.Arguments, env)
+		if len(args) == 1 && (isError(args[0]) || isControlSignal(args[0])) {
+			return args[0]
+		}
+		return applyFunction(function, args)
+	case *ast.ArrayLiteral:
+		elements := evalExpressions(node.Elements, env)
+		if len(elements) == 1 && (isError(elements[0]) || isControlSignal(elements[0])) {
+			return elements[0]
+		}
+		return &object.Array{Elements: elements}
+	case *ast.IndexExpression:
+		left := Eval(node.Left, env)
+		if isError(left) || isControlSignal(left) {
+			return left
+		}
+		index := Eval(node.Index, env)
+		if isError(index) || isControlSignal(index) {
+			return index
+		}
+		return evalIndexExpression(left, index)
+	case *ast.HashLiteral:
+		return evalHashLiteral(node, env)
+	case *ast.AssignExpression:
+		return evalAssignExpression(node, env)
 	}
 	return nil
 }
 
-func evalIfExpression(ie *ast.IfExpression) object.Object {
-	condition := Eval(ie.Condition)
+// evalAssignExpression 对赋值表达式求值，整个表达式的值为赋值之后的新值
+func evalAssignExpression(node *ast.AssignExpression, env *object.Environment) object.Object {
+	switch target := node.Target.(type) {
+	case *ast.Identifier:
+		current, ok := env.Get(target.Value)
+		if !ok { //赋值只能更新已有的绑定，新的绑定必须通过let创建
+			return newError("assignment to undeclared identifier: %s", target.Value)
+		}
+		if env.IsConst(target.Value) {
+			return newError("cannot assign to constant %s", target.Value)
+		}
+		val := evalAssignedValue(node, current, env)
+		if isError(val) || isControlSignal(val) {
+			return val
+		}
+		env.Assign(target.Value, val)
+		return val
+	case *ast.IndexExpression:
+		left := Eval(target.Left, env)
+		if isError(left) || isControlSignal(left) {
+			return left
+		}
+		index := Eval(target.Index, env)
+		if isError(index) || isControlSignal(index) {
+			return index
+		}
+		var current object.Object
+		if node.Operator != "=" {
+			current = evalIndexExpression(left, index)
+			if isError(current) {
+				return current
+			}
+		}
+		val := evalAssignedValue(node, current, env)
+		if isError(val) || isControlSignal(val) {
+			return val
+		}
+		return evalIndexAssignment(left, index, val)
+	default:
+		return newError("invalid assignment target: %s", node.Target.String())
+	}
+}
+
+// evalAssignedValue 对赋值运算符右侧求值，复合赋值运算符会先与当前值current进行运算
+func evalAssignedValue(node *ast.AssignExpression, current object.Object, env *object.Environment) object.Object {
+	val := Eval(node.Value, env)
+	if isError(val) || isControlSignal(val) || node.Operator == "=" {
+		return val
+	}
+	return evalInfixExpression(node.Operator[:len(node.Operator)-1], current, val, env) //+=对应+，依此类推
+}
+
+// evalIndexAssignment 原地修改数组或者哈希表中的元素，数组下标越界时返回错误而不是像读取一样返回NULL
+func evalIndexAssignment(left, index, val object.Object) object.Object {
+	switch left := left.(type) {
+	case *object.Array:
+		idx, ok := index.(*object.Integer)
+		if !ok {
+			return newError("array index must be INTEGER, got %s", index.Type())
+		}
+		if idx.Value < 0 || idx.Value >= int64(len(left.Elements)) {
+			return newError("index out of range: %d (length %d)", idx.Value, len(left.Elements))
+		}
+		left.Elements[idx.Value] = val
+		return val
+	case *object.Hash:
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return newError("unusable as hash key: %s", index.Type())
+		}
+		left.Set(key.HashKey(), object.HashPair{Key: index, Value: val})
+		return val
+	default:
+		return newError("index assignment not supported: %s", left.Type())
+	}
+}
+
+func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
+	hash := object.NewHash()
+
+	for _, keyNode := range node.Keys { //按照源代码中的顺序求值，保证副作用和错误的顺序是确定的
+		key := Eval(keyNode, env)
+		if isError(key) || isControlSignal(key) {
+			return key
+		}
+
+		hashKey, ok := key.(object.Hashable)
+		if !ok {
+			return newError("unusable as hash key: %s", key.Type())
+		}
+
+		value := Eval(node.Pairs[keyNode], env)
+		if isError(value) || isControlSignal(value) {
+			return value
+		}
+
+		hash.Set(hashKey.HashKey(), object.HashPair{Key: key, Value: value})
+	}
+
+	return hash
+}
+
+func evalIndexExpression(left, index object.Object) object.Object {
+	switch {
+	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
+		return evalArrayIndexExpression(left, index)
+	case left.Type() == object.ARRAY_OBJ: //与索引赋值报告相同的错误
+		return newError("array index must be INTEGER, got %s", index.Type())
+	case left.Type() == object.HASH_OBJ:
+		return evalHashIndexExpression(left, index)
+	default:
+		return newError("index operator not supported: %s", left.Type())
+	}
+}
+
+// evalArrayIndexExpression 对数组进行下标访问，下标越界（包括负数下标）时返回NULL而不是错误
+func evalArrayIndexExpression(array, index object.Object) object.Object {
+	arrayObject := array.(*object.Array)
+	idx := index.(*object.Integer).Value
+	max := int64(len(arrayObject.Elements) - 1)
+
+	if idx < 0 || idx > max {
+		return NULL
+	}
+	return arrayObject.Elements[idx]
+}
+
+// evalHashIndexExpression 在哈希表中查找键，找不到时返回NULL
+func evalHashIndexExpression(hash, index object.Object) object.Object {
+	hashObject := hash.(*object.Hash)
+
+	key, ok := index.(object.Hashable)
+	if !ok {
+		return newError("unusable as hash key: %s", index.Type())
+	}
+
+	pair, ok := hashObject.Pairs[key.HashKey()]
+	if !ok {
+		return NULL
+	}
+	return pair.Value
+}
+
+// evalExpressions 从左到右依次对参数表达式求值，遇到错误时只返回该错误
+func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
+	var result []object.Object
+
+	for _, e := range exps {
+		evaluated := Eval(e, env)
+		if isError(evaluated) || isControlSignal(evaluated) {
+			return []object.Object{evaluated}
+		}
+		result = append(result, evaluated)
+	}
+	return result
+}
+
+func applyFunction(fn object.Object, args []object.Object) object.Object {
+	switch function := fn.(type) {
+	case *object.Function:
+		if len(args) != len(function.Parameters) {
+			return newError("wrong number of arguments: want=%d, got=%d", len(function.Parameters), len(args))
+		}
+
+		extendedEnv := extendFunctionEnv(function, args)
+		evaluated := Eval(function.Body, extendedEnv)
+		return unwrapReturnValue(evaluated)
+	case *object.Builtin:
+		return function.Fn(args...)
+	default:
+		return newError("not a function: %s", fn.Type())
+	}
+}
+
+// unwrapReturnValue 在函数调用边界处解开返回值的包装，避免函数内部的return终止外层的求值
+func unwrapReturnValue(obj object.Object) object.Object {
+	if returnValue, ok := obj.(*object.ReturnValue); ok {
+		return returnValue.Value
+	}
+	return obj
+}
+
+// extendFunctionEnv 以函数定义时的环境为外层作用域，创建新的环境并绑定实参
+func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
+	env := object.NewEnclosedEnvironment(fn.Env)
+
+	for i, param := range fn.Parameters {
+		env.Set(param.Value, args[i])
+	}
+	return env
+}
+
+func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
+	if val, ok := env.Get(node.Value); ok {
+		return val
+	}
+	if builtin, ok := builtins[node.Value]; ok { //用户绑定优先于内置函数
+		return builtin
+	}
+	return newError("identifier not found: " + node.Value)
+}
+
+func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
+	condition := Eval(ie.Condition, env)
+	if isError(condition) || isControlSignal(condition) {
+		return condition
+	}
 	if isTruthy(condition) {
-		return Eval(ie.Consequence)
+		return Eval(ie.Consequence, env)
 	} else if ie.Alternative != nil {
-		return Eval(ie.Alternative)
+		return Eval(ie.Alternative, env)
 	} else {
 		return NULL
 	}
 }
 
+// evalWhileExpression 在条件为真时重复执行循环体，循环本身的值为NULL。
+// 与for循环一样，每一次迭代都使用新的作用域，循环体中的let和const不会残留到下一次迭代
+func evalWhileExpression(we *ast.WhileExpression, env *object.Environment) object.Object {
+	for {
+		condition := Eval(we.Condition, env)
+		if isError(condition) || isControlSignal(condition) {
+			return condition
+		}
+		if !isTruthy(condition) {
+			return NULL
+		}
+		if stop, result := loopControl(Eval(we.Body, object.NewEnclosedEnvironment(env))); stop {
+			return result
+		}
+	}
+}
+
+// evalForInExpression 依次将数组的元素或者字符串的字符绑定到循环变量上执行循环体。
+// 每一次迭代都使用新的作用域，因此循环体中创建的闭包捕获的是各自迭代的变量
+func evalForInExpression(fe *ast.ForInExpression, env *object.Environment) object.Object {
+	iterable := Eval(fe.Iterable, env)
+	if isError(iterable) || isControlSignal(iterable) {
+		return iterable
+	}
+
+	var elements []object.Object
+	switch iterable := iterable.(type) {
+	case *object.Array:
+		elements = iterable.Elements
+	case *object.String:
+		for _, r := range iterable.Value {
+			elements = append(elements, &object.String{Value: string(r)})
+		}
+	default:
+		err := newError("cannot iterate over %s", iterable.Type())
+		err.Pos = fe.Iterable.Pos()
+		return err
+	}
+
+	for _, element := range elements {
+		loopEnv := object.NewEnclosedEnvironment(env)
+		loopEnv.Set(fe.Variable.Value, element)
+		if stop, result := loopControl(Eval(fe.Body, loopEnv)); stop {
+			return result
+		}
+	}
+	return NULL
+}
+
+// loopControl 根据一次循环体的求值结果判断是否需要结束循环。
+// return和错误需要继续向外传递，break只结束当前循环，continue与正常执行完循环体相同
+func loopControl(result object.Object) (bool, object.Object) {
+	switch result.(type) {
+	case *object.ReturnValue, *object.Error:
+		return true, result
+	case *object.Break:
+		return true, NULL
+	}
+	return false, nil
+}
+
+func evalTernaryExpression(te *ast.TernaryExpression, env *object.Environment) object.Object {
+	condition := Eval(te.Condition, env)
+	if isError(condition) || isControlSignal(condition) {
+		return condition
+	}
+	if isTruthy(condition) {
+		return Eval(te.Consequence, env)
+	}
+	return Eval(te.Alternative, env)
+}
+
+// evalMatchExpression 按顺序尝试每一个分支，模式中的绑定位于每个分支各自的作用域中，没有分支匹配时返回错误
+func evalMatchExpression(me *ast.MatchExpression, env *object.Environment) object.Object {
+	subject := Eval(me.Subject, env)
+	if isError(subject) || isControlSignal(subject) {
+		return subject
+	}
+
+	for _, arm := range me.Arms {
+		armEnv := object.NewEnclosedEnvironment(env)
+		if !matchPattern(arm.Pattern, subject, armEnv) {
+			continue
+		}
+		if arm.Guard != nil {
+			guard := Eval(arm.Guard, armEnv)
+			if isError(guard) || isControlSignal(guard) {
+				return guard
+			}
+			if !isTruthy(guard) {
+				continue
+			}
+		}
+		return Eval(arm.Body, armEnv)
+	}
+	return newError("no match arm for value: %s", subject.Inspect())
+}
+
+// matchPattern 判断value是否与pattern匹配，并将模式中的标识符绑定到env中。
+// 数组模式要求长度相同，哈希模式只要求value包含模式中的所有键，字面量按照==的规则比较
+func matchPattern(pattern ast.Expression, value object.Object, env *object.Environment) bool {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		if pattern.Value != "_" {
+			env.Set(pattern.Value, value)
+		}
+		return true
+	case *ast.ArrayLiteral:
+		array, ok := value.(*object.Array)
+		if !ok || len(array.Elements) != len(pattern.Elements) {
+			return false
+		}
+		for i, element := range pattern.Elements {
+			if !matchPattern(element, array.Elements[i], env) {
+				return false
+			}
+		}
+		return true
+	case *ast.HashLiteral:
+		hash, ok := value.(*object.Hash)
+		if !ok {
+			return false
+		}
+		for _, keyNode := range pattern.Keys {
+			key, ok := Eval(keyNode, env).(object.Hashable) //解析器保证键是字面量
+			if !ok {
+				return false
+			}
+			pair, ok := hash.Pairs[key.HashKey()]
+			if !ok || !matchPattern(pattern.Pairs[keyNode], pair.Value, env) {
+				return false
+			}
+		}
+		return true
+	default:
+		return evalInfixExpression("==", value, Eval(pattern, env), env) == TRUE
+	}
+}
+
 func isTruthy(obj object.Object) bool {
 	switch obj {
 	case NULL:
@@ -61,64 +505,148 @@ func isTruthy(obj object.Object) bool {
 	}
 }
 
-func evalInfixExpression(operator string, left object.Object, right object.Object) object.Object {
+// evalLogicalExpression 对&&和||进行短路求值，结果为决定整个表达式真假的那个操作数本身，而不是转换后的布尔值
+func evalLogicalExpression(node *ast.InfixExpression, env *object.Environment) object.Object {
+	left := Eval(node.Left, env)
+	if isError(left) || isControlSignal(left) {
+		return left
+	}
+	if isTruthy(left) == (node.Operator == "||") { //左侧已经能够决定结果时不再对右侧求值
+		return left
+	}
+	return Eval(node.Right, env)
+}
+
+func evalInfixExpression(operator string, left object.Object, right object.Object, env *object.Environment) object.Object {
 	switch {
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
-		return evalIntegerInfixExpression(operator, left, right)
+		return evalIntegerInfixExpression(operator, left, right, env)
+	case isIntegerLike(left) && isIntegerLike(right): //至少有一侧是溢出后提升得到的BigInteger
+		return evalBigIntegerInfixExpression(operator, left, right)
+	case isNumeric(left) && isNumeric(right): //至少有一侧是浮点数，整数会先被转换为浮点数
+		return evalFloatInfixExpression(operator, left, right)
+	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
+		return evalStringInfixExpression(operator, left, right)
 
-	case operator == "==": //TODO: 修改这里的判断条件，只允许bool类型进行相等和不相等的匹配运算
+	case operator == "==":
 		return nativeBoolToBooleanObject(left == right)
 	case operator == "!=":
 		return nativeBoolToBooleanObject(left != right)
+	case left.Type() != right.Type(): //类型不一致时无法进行运算
+		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
 	default:
-		return NULL
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
-func evalIntegerInfixExpression(operator string, left object.Object, right object.Object) object.Object {
+func evalIntegerInfixExpression(operator string, left object.Object, right object.Object, env *object.Environment) object.Object {
 	leftVal := left.(*object.Integer).Value
 	rightVal := right.(*object.Integer).Value
 	switch operator {
-	case "+":
-		return &object.Integer{Value: leftVal + rightVal}
-	case "-":
-		return &object.Integer{Value: leftVal - rightVal}
-	case "*":
-		return &object.Integer{Value: leftVal * rightVal}
+	case "+", "-", "*":
+		return evalIntegerArithmetic(operator, leftVal, rightVal, env)
 	case "/":
-		return &object.Integer{Value: leftVal / rightVal}
+		if rightVal == 0 { //除数为0时直接返回错误，避免Go运行时panic
+			return newError("division by zero")
+		}
+		return evalIntegerArithmetic(operator, leftVal, rightVal, env)
+	case "%":
+		if rightVal == 0 {
+			return newError("division by zero")
+		}
+		return &object.Integer{Value: leftVal % rightVal} //取余的结果不会超出int64的范围
+	case "&":
+		return &object.Integer{Value: leftVal & rightVal}
+	case "|":
+		return &object.Integer{Value: leftVal | rightVal}
+	case "^":
+		return &object.Integer{Value: leftVal ^ rightVal}
+	case "<<":
+		if rightVal < 0 { //负数的移位位数会导致Go运行时panic
+			return newError("negative shift count: %d", rightVal)
+		}
+		return evalIntegerArithmetic(operator, leftVal, rightVal, env)
+	case ">>":
+		if rightVal < 0 {
+			return newError("negative shift count: %d", rightVal)
+		}
+		return &object.Integer{Value: leftVal >> uint64(rightVal)} //算术右移，不会溢出
 
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
 	case ">":
 		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
 	case "==":
 		return nativeBoolToBooleanObject(leftVal == rightVal)
 	case "!=":
 		return nativeBoolToBooleanObject(leftVal != rightVal)
 	default:
-		return NULL
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 
 }
 
-func evalPrefixExpression(operator string, right object.Object) object.Object {
+func evalStringInfixExpression(operator string, left object.Object, right object.Object) object.Object {
+	leftVal := left.(*object.String).Value
+	rightVal := right.(*object.String).Value
+	switch operator {
+	case "+":
+		return &object.String{Value: leftVal + rightVal}
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+func evalPrefixExpression(operator string, right object.Object, env *object.Environment) object.Object {
 	switch operator {
 	case "!":
 		return evalBangOperatorExpression(right)
 	case "-":
-		return evalMinusPrefixOperatorExpression(right)
+		return evalMinusPrefixOperatorExpression(right, env)
+	case "~":
+		return evalTildePrefixOperatorExpression(right)
 	default:
-		return NULL
+		return newError("unknown operator: %s%s", operator, right.Type())
 	}
 }
 
-func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
-	if right.Type() != object.INTEGER_OBJ {
-		return NULL
+func evalMinusPrefixOperatorExpression(right object.Object, env *object.Environment) object.Object {
+	switch right := right.(type) {
+	case *object.Integer:
+		if right.Value == math.MinInt64 && env.OverflowMode() != object.OverflowWrap { //-math.MinInt64同样会溢出
+			if env.OverflowMode() == object.OverflowError {
+				return newError("integer overflow: -(%d)", right.Value)
+			}
+			return normalizeBigInteger(new(big.Int).Neg(big.NewInt(right.Value)))
+		}
+		return &object.Integer{Value: -right.Value}
+	case *object.BigInteger:
+		return normalizeBigInteger(new(big.Int).Neg(right.Value))
+	case *object.Float:
+		return &object.Float{Value: -right.Value}
+	default:
+		return newError("unknown operator: -%s", right.Type())
+	}
+}
+
+// evalTildePrefixOperatorExpression 对整数按位取反，~x等于-x-1，因此不会溢出
+func evalTildePrefixOperatorExpression(right object.Object) object.Object {
+	switch right := right.(type) {
+	case *object.Integer:
+		return &object.Integer{Value: ^right.Value}
+	case *object.BigInteger:
+		return normalizeBigInteger(new(big.Int).Not(right.Value))
+	default:
+		return newError("unknown operator: ~%s", right.Type())
 	}
-	value := right.(*object.Integer).Value
-	return &object.Integer{Value: -value}
 }
 
 func evalBangOperatorExpression(right object.Object) object.Object {
@@ -134,10 +662,37 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 	}
 }
 
-func evalStatements(statements []ast.Statement) object.Object {
+func evalProgram(program *ast.Program, env *object.Environment) object.Object {
+	var result object.Object
+	for _, statement := range program.Statements { //最基本的迭代式框架，遍历statements语句
+		result = Eval(statement, env)
+
+		switch result := result.(type) {
+		case *object.ReturnValue: //在最外层遇到return时解开包装并停止求值
+			return result.Value
+		case *object.Error: //出现错误时立即停止求值
+			return result
+		}
+	}
+	return result
+}
+
+// evalBlockStatement 遇到return时不解开包装，直接将ReturnValue向上传递，使嵌套的代码块也能够提前退出。
+// break和continue同样会结束当前代码块，交给外层的循环处理
+func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
 	var result object.Object
-	for _, statement := range statements { //最基本的迭代式框架，遍历statements语句
-		result = Eval(statement)
+	for _, statement := range block.Statements {
+		result = Eval(statement, env)
+
+		if result != nil {
+			rt := result.Type()
+			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ || rt == object.BREAK_OBJ || rt == object.CONTINUE_OBJ {
+				return result
+			}
+		}
+	}
+	if result == nil { //空代码块或者以let、const语句结尾的代码块，值为null
+		return NULL
 	}
 	return result
 }
@@ -148,3 +703,27 @@ func nativeBoolToBooleanObject(input bool) *object.Boolean {
 	}
 	return FALSE
 }
+
+func newError(format string, a ...interface{}) *object.Error {
+	return &object.Error{Message: fmt.Sprintf(format, a...)}
+}
+
+// isControlSignal 判断obj是否是return、break或continue产生的控制信号。控制信号与错误一样需要立即结束当前表达式的求值并向外传递，
+// 直到遇到能够处理它的循环、函数调用或者程序
+func isControlSignal(obj object.Object) bool {
+	if obj == nil {
+		return false
+	}
+	switch obj.Type() {
+	case object.RETURN_VALUE_OBJ, object.BREAK_OBJ, object.CONTINUE_OBJ:
+		return true
+	}
+	return false
+}
+
+func isError(obj object.Object) bool {
+	if obj != nil {
+		return obj.Type() == object.ERROR_OBJ
+	}
+	return false
+}
@@ -27,6 +27,18 @@ func TestEvalIntegerTestExpression(t *testing.T) {
 		{"3 * 3 * 3 + 10", 37},
 		{"3 * (3 * 3 ) + 10", 37},
 		{"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+		{"7 % 3", 1},
+		{"-7 % 3", -1},
+		{"6 & 3", 2},
+		{"6 | 3", 7},
+		{"6 ^ 3", 5},
+		{"1 << 10", 1024},
+		{"-16 >> 2", -4},
+		{"1 << 64", 0},
+		{"~5", -6},
+		{"~-1", 0},
+		{"1 + 2 << 3", 24},
+		{"5 & 3 + 1", 4},
 	}
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
@@ -38,8 +50,20 @@ func testEval(input string) object.Object {
 	l := lexer.New(input)
 	p := parser.New(l)
 	program := p.ParseProgram()
+	env := object.NewEnvironment()
 
-	return Eval(program)
+	return Eval(program, env)
+}
+
+// testEvalWithOverflow 与testEval相同，但使用指定的整数溢出处理方式求值
+func testEvalWithOverflow(input string, mode object.OverflowMode) object.Object {
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	env := object.NewEnvironment()
+	env.SetOverflowMode(mode)
+
+	return Eval(program, env)
 }
 
 func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
@@ -79,6 +103,15 @@ func TestEvalBooleanExpression(t *testing.T) {
 		{"(1 < 2) == false", false},
 		{"(1 > 2) == true", false},
 		{"(1 > 2) == false", true},
+		{"1 <= 2", true},
+		{"2 <= 2", true},
+		{"3 <= 2", false},
+		{"1 >= 2", false},
+		{"2 >= 2", true},
+		{"1 < 2 && 2 < 3", true},
+		{"1 < 2 && 3 < 2", false},
+		{"1 > 2 || 2 < 3", true},
+		{"1 > 2 || 3 < 2", false},
 	}
 
 	for _, tt := range tests {
@@ -120,6 +153,43 @@ func TestBangOperator(t *testing.T) {
 
 }
 
+func TestLogicalOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"1 && 2", 2},
+		{"false && 2", false},
+		{"0 || 2", 0},
+		{"false || 2", 2},
+		{"if (false) { 1 } || 3", 3},
+		{`"a" && "b"`, "b"},
+		{"false && x", false}, //右侧未定义的标识符不会被求值
+		{"true || undefinedFunction()", true},
+		{"let a = [1]; len(a) > 0 && a[0] == 1", true},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok || str.Value != expected {
+				t.Errorf("object is not String %q. got=%T (%+v)", expected, evaluated, evaluated)
+			}
+		}
+	}
+
+	evaluated := testEval("true && x")
+	if errObj, ok := evaluated.(*object.Error); !ok || errObj.Message != "identifier not found: x" {
+		t.Errorf("expected identifier not found error. got=%T(%+v)", evaluated, evaluated)
+	}
+}
+
 func TestIfElseExpression(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -131,6 +201,11 @@ func TestIfElseExpression(t *testing.T) {
 		{"if (1 > 2) {10}", nil},
 		{"if (1 > 2) {10} else {20}", 20},
 		{"if (1 < 2) {10} else {20}", 10},
+		{"if (1 > 2) {10} else if (2 > 1) {20} else {30}", 20},
+		{"if (1 > 2) {10} else if (2 > 3) {20} else {30}", 30},
+		{"if (1 > 2) {10} else if (2 > 3) {20}", nil},
+		{"let grade = fn(n) { if (n >= 90) { 4 } else if (n >= 80) { 3 } else if (n >= 70) { 2 } else { 0 } }; grade(85)", 3},
+		{"let f = fn(n) { if (n > 0) { return 1 } else if (n < 0) { return -1 } 0 }; f(-5)", -1},
 	}
 
 	for _, tt := range tests {
@@ -151,3 +226,1181 @@ func testNullObject(t *testing.T, obj object.Object) bool {
 	}
 	return true
 }
+
+func TestWhileExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let i = 0; while (i < 5) { i = i + 1 }; i", 5},
+		{"while (false) { 1 }", nil},
+		{"let i = 0; while (true) { i = i + 1; if (i == 3) { break } }; i", 3},
+		{"let i = 0; let n = 0; while (i < 5) { i = i + 1; if (i % 2 == 0) { continue } n = n + i }; n", 9},
+		{"let f = fn() { let i = 0; while (true) { i = i + 1; if (i > 10) { return i } } }; f()", 11},
+		{"let i = 0; while (i < 100000) { i = i + 1 }; i", 100000},
+		{"let i = 0; while (i < 3) { let i = 10; i; break }; i", 0}, //循环体中的let只在本次迭代中可见
+		{"let i = 0; let n = 0; while (i < 3) { const k = i * 2; n += k; i += 1 }; n", 6},
+		{"let i = 0; while (i < 3) { if (i > 0) { const k = i; } i += 1 }; i", 3},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if expected, ok := tt.expected.(int); ok {
+			testIntegerObject(t, evaluated, int64(expected))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestForInExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let sum = 0; for (x in [1, 2, 3]) { let sum = sum + x }; sum", 0}, //循环体在新的作用域中执行，不影响外层的绑定
+		{"let f = fn(xs) { for (x in xs) { if (x > 2) { return x } } }; f([1, 2, 3, 4])", 3},
+		{`let find = fn(s) { for (c in s) { if (c == "é") { return 1 } } 0 }; find("héllo")`, 1},
+		{"let x = 10; for (x in [1, 2]) { x }; x", 10},
+		{"for (x in []) { x }", nil},
+		{"for (x in [1, 2]) { break }", nil},
+		{"let f = fn() { for (x in [1, 2, 3]) { if (x < 3) { continue } return x } }; f()", 3},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if expected, ok := tt.expected.(int); ok {
+			testIntegerObject(t, evaluated, int64(expected))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestLoopControlInExpressions(t *testing.T) {
+	//break和continue出现在表达式中时也要作用于外层的循环，而不是被当作普通的值
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let i = 0; while (true) { i += 1; let x = if (i > 3) { break; }; }; i", 4},
+		{"let n = 0; for (x in [1, 2, 3, 4]) { n += 1; const y = if (x > 2) { break; } else { x }; }; n", 3},
+		{"let i = 0; while (true) { i += 1; len(if (i > 2) { break; } else { \"\" }); }; i", 3},
+		{"let i = 0; while (true) { i += 1; 1 + if (i > 2) { break; } else { 0 } }; i", 3},
+		{"let i = 0; while (true) { i += 1; -if (i > 2) { break; } else { 0 } }; i", 3},
+		{"let i = 0; while (true) { i += 1; [1][if (i > 2) { break; } else { 0 }] }; i", 3},
+		{"let i = 0; while (true) { i += 1; [if (i > 2) { break; }] }; i", 3},
+		{"let n = 0; for (x in [1, 2, 3, 4]) { n += if (x % 2 == 0) { continue; } else { x } }; n", 4},
+		{"let n = 0; for (x in [1, 2, 3]) { n = n + len([if (x == 2) { continue; } else { x }]) }; n", 2},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestLoopsWithArrays(t *testing.T) {
+	input := `
+let collect = fn(xs, skip, stop) {
+	let result = [];
+	let i = 0;
+	while (i < len(xs)) {
+		let x = xs[i];
+		i = i + 1;
+		if (x == skip) { continue }
+		if (x == stop) { break }
+		result = push(result, x);
+	}
+	result
+};
+collect([1, 2, 3, 4, 5, 6], 2, 5)`
+
+	evaluated := testEval(input)
+	result, ok := evaluated.(*object.Array)
+	if !ok {
+		t.Fatalf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+	}
+	if result.Inspect() != "[1, 3, 4]" {
+		t.Errorf("wrong result. got=%s", result.Inspect())
+	}
+
+	input = `
+let nested = fn() {
+	for (a in [1, 2, 3]) {
+		for (b in [10, 20]) {
+			if (a < 3) { break } //只跳出内层循环
+			return a * b;
+		}
+	}
+};
+nested()`
+	testIntegerObject(t, testEval(input), 30)
+}
+
+func TestLoopErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"while (x) { 1 }", "identifier not found: x"},
+		{"let i = 0; while (true) { i = i + 1; if (i == 3) { i + true } }", "type mismatch: INTEGER + BOOLEAN"},
+		{"for (x in 5) { x }", "cannot iterate over INTEGER"},
+		{"for (x in [1, 2]) { x + \"a\" }", "type mismatch: INTEGER + STRING"},
+		{"for (x in [1]) { y }; 1", "identifier not found: y"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func TestAssignExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let x = 1; x = 5; x", 5},
+		{"let x = 1; x = x + 1", 2},
+		{"let x = 10; x += 5; x", 15},
+		{"let x = 10; x -= 5; x", 5},
+		{"let x = 10; x *= 5; x", 50},
+		{"let x = 10; x /= 5; x", 2},
+		{"let a = 1; let b = 2; a = b = 7; a + b", 14},
+		{"let x = 1; let f = fn() { x = 2 }; f(); x", 2},            //更新外层作用域中的绑定
+		{"let x = 1; let f = fn() { let x = 5; x = 2 }; f(); x", 1}, //只更新最近的绑定
+		{"let counter = fn() { let n = 0; fn() { n += 1 } }; let c = counter(); c(); c(); c()", 3},
+		{"let i = 0; let sum = 0; while (i < 5) { i += 1; sum += i }; sum", 15},
+		{"let sum = 0; for (x in [1, 2, 3]) { sum += x }; sum", 6},
+		{"let arr = [1, 2, 3]; arr[0] = 5; arr[0] + arr[1]", 7},
+		{"let arr = [1, 2, 3]; arr[2] *= 10; arr[2]", 30},
+		{"let a = [1]; let b = a; b[0] = 9; a[0]", 9}, //数组按引用共享
+		{`let h = {"k": 1}; h["k"] = 5; h["k"]`, 5},
+		{`let h = {}; h["new"] = 3; h["new"] += 1; h["new"]`, 4},
+		{`let h = {"xs": [1, 2]}; h["xs"][1] = 8; h["xs"][1]`, 8},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+
+	closures := `
+let fns = [];
+for (x in [1, 2, 3]) {
+	fns = push(fns, fn() { x });
+}
+fns[0]() * 100 + fns[1]() * 10 + fns[2]()`
+	testIntegerObject(t, testEval(closures), 123) //每一次迭代的闭包捕获各自的循环变量
+
+	cyclic := testEval("let a = [1]; a[0] = a; a") //通过索引赋值构造的环不能让Inspect无限递归
+	if cyclic.Inspect() != "[[...]]" {
+		t.Errorf("wrong Inspect for cyclic array. got=%q", cyclic.Inspect())
+	}
+}
+
+func TestAssignmentErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"x = 5", "assignment to undeclared identifier: x"},
+		{"let f = fn() { y = 1 }; f()", "assignment to undeclared identifier: y"},
+		{"len = 1", "assignment to undeclared identifier: len"},
+		{"let x = 1; x += true", "type mismatch: INTEGER + BOOLEAN"},
+		{"let x = 1; x /= 0", "division by zero"},
+		{"let arr = [1]; arr[1] = 2", "index out of range: 1 (length 1)"},
+		{"let arr = [1]; arr[-1] = 2", "index out of range: -1 (length 1)"},
+		{`let arr = [1]; arr["a"] = 2`, "array index must be INTEGER, got STRING"},
+		{"let h = {}; h[fn(x) { x }] = 1", "unusable as hash key: FUNCTION"},
+		{`let s = "abc"; s[0] = "x"`, "index assignment not supported: STRING"},
+		{"let x = 1; x = y", "identifier not found: y"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func TestConstStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"const a = 5; a", 5},
+		{"const a = 5; const b = a * 2; b", 10},
+		{"const a = 5; let f = fn() { let a = 1; a += 1; a }; f() + a", 7},
+		{"const a = 5; let f = fn(a) { a = a * 2; a }; f(3)", 6},
+		{"const xs = [1, 2]; xs[0] = 9; xs[0]", 9}, //常量只保证绑定不变
+		{"let a = 1; const a = 2; a", 2},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestConstViolations(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		//这些情况在解析阶段无法发现，只能在运行时检查
+		{"let f = fn() { limit = 2 }; const limit = 1; f()", "cannot assign to constant limit"},
+		{"let f = fn() { limit += 2 }; const limit = 1; f(); limit", "cannot assign to constant limit"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expectedMessage, errObj.Message)
+		}
+	}
+
+	//在同一个环境中分多次求值（例如REPL）时，解析器看不到之前的声明
+	env := object.NewEnvironment()
+	lines := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"const limit = 10;", ""},
+		{"limit = 20;", "cannot assign to constant limit"},
+		{"let limit = 20;", "cannot redeclare constant limit"},
+		{"const limit = 20;", "cannot redeclare constant limit"},
+		{"let f = fn() { let limit = 1; limit }; f()", ""},
+	}
+	for _, line := range lines {
+		p := parser.New(lexer.New(line.input))
+		evaluated := Eval(p.ParseProgram(), env)
+		errObj, isErr := evaluated.(*object.Error)
+		if line.expectedMessage == "" {
+			if isErr {
+				t.Errorf("unexpected error for %q: %s", line.input, errObj.Message)
+			}
+			continue
+		}
+		if !isErr || errObj.Message != line.expectedMessage {
+			t.Errorf("wrong result for %q. expected error %q, got=%T(%+v)", line.input, line.expectedMessage, evaluated, evaluated)
+		}
+	}
+	if val, _ := env.Get("limit"); val.(*object.Integer).Value != 10 {
+		t.Errorf("constant was modified. got=%s", val.Inspect())
+	}
+}
+
+func TestTernaryExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"true ? 1 : 2", 1},
+		{"false ? 1 : 2", 2},
+		{"null_value ? 1 : 2", "identifier not found: null_value"},
+		{"0 ? 1 : 2", 1}, //与if相同，只有false和NULL为假
+		{"if (false) { 1 } ? 1 : 2", 2},
+		{"let abs = fn(n) { n < 0 ? -n : n }; abs(-7)", 7},
+		{"let sign = fn(n) { n > 0 ? 1 : n < 0 ? -1 : 0 }; sign(-3) * 10 + sign(0)", -10},
+		{"let x = 0; true ? 1 : (x = 5); x", 0}, //未被选中的分支不会求值
+		{"let x = 0; x = 1 > 2 ? 10 : 20; x", 20},
+		{"true ? 1 + true : 2", "type mismatch: INTEGER + BOOLEAN"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok || errObj.Message != expected {
+				t.Errorf("expected error %q. got=%T(%+v)", expected, evaluated, evaluated)
+			}
+		}
+	}
+}
+
+func TestMatchExpressions(t *testing.T) {
+	describe := `
+let describe = fn(v) {
+	match (v) {
+		0 => "zero",
+		-1 => "minus one",
+		1.5 => "one and a half",
+		"hi" => "greeting",
+		true => "yes",
+		[] => "empty",
+		[x] => "one element " + type(x),
+		[a, b] if a == b => "pair of equals",
+		[a, b] => "pair",
+		{"type": "circle", "r": r} => "circle " + type(r),
+		{"type": t} => t,
+		n if type(n) == "INTEGER" && n > 100 => "big",
+		_ => "other",
+	}
+};
+`
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"describe(0)", "zero"},
+		{"describe(-1)", "minus one"},
+		{"describe(1.5)", "one and a half"},
+		{`describe("hi")`, "greeting"},
+		{"describe(true)", "yes"},
+		{"describe([])", "empty"},
+		{`describe(["a"])`, "one element STRING"},
+		{"describe([1, 1])", "pair of equals"},
+		{"describe([1, 2])", "pair"},
+		{"describe([1, 2, 3])", "other"},
+		{`describe({"type": "circle", "r": 2})`, "circle INTEGER"},
+		{`describe({"type": "square", "side": 2})`, "square"},
+		{`describe({"kind": "square"})`, "other"},
+		{"describe(1000)", "big"},
+		{"describe(50)", "other"},
+		{"describe(false)", "other"},
+		{`describe("1")`, "other"}, //不同类型的值不会相等
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(describe + tt.input)
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("%s: object is not String. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if str.Value != tt.expected {
+			t.Errorf("%s: wrong result. expected=%q, got=%q", tt.input, tt.expected, str.Value)
+		}
+	}
+
+	intTests := []struct {
+		input    string
+		expected int64
+	}{
+		{"match ([1, [2, 3]]) { [a, [b, c]] => a + b + c }", 6},
+		{"match (2) { 2.0 => 1, _ => 0 }", 1},       //字面量按照==比较，整数与浮点数可以相等
+		{"let x = 10; match (5) { x => x }; x", 10}, //模式中的绑定只在分支内部可见
+		{"let x = 10; match (5) { x => x }", 5},
+		{"let f = fn(xs) { match (xs) { [] => 0, [h] => h, _ => f(rest(xs)) + xs[0] } }; f([1, 2, 3, 4])", 10},
+		{"let n = 0; let count = fn() { n += 1; n }; match (count()) { 5 => 5, _ => n }", 1}, //只对被匹配的值求值一次
+		{"let seen = 0; match (1) { x if (seen = seen + 1) > 5 => 0, _ => seen }", 1},
+		{"match (1) { _ => { 1 } }", 1}, //=>之后的{是代码块
+		{"match ([2, 3]) { [a, b] => { let c = a * b; c + 1 }, _ => 0 }", 7},
+		{`match (1) { _ => ({"k": 4}) }["k"]`, 4},
+		{"let f = fn(x) { match (x) { 1 => { return 10; }, _ => 0 }; 20 }; f(1)", 10},
+	}
+
+	for _, tt := range intTests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+
+	errorTests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"match (3) { 1 => 1, 2 => 2 }", "no match arm for value: 3"},
+		{`match ([1, "a"]) { [x] => x }`, "no match arm for value: [1, a]"},
+		{"match (y) { _ => 1 }", "identifier not found: y"},
+		{"match (1) { x if x + true => 1 }", "type mismatch: INTEGER + BOOLEAN"},
+		{"match (1) { x => x + true }", "type mismatch: INTEGER + BOOLEAN"},
+	}
+
+	for _, tt := range errorTests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func TestEmptyBlocksAreNull(t *testing.T) {
+	//空代码块和以let、const结尾的代码块的值是NULL，不能是Go中的nil，否则后续的运算会panic
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let a = if (true) { let x = 1; }; a + 1", "type mismatch: NULL + INTEGER"},
+		{"let a = if (true) { const x = 1; }; a + 1", "type mismatch: NULL + INTEGER"},
+		{"let f = fn() {}; f() + 1", "type mismatch: NULL + INTEGER"},
+		{"let x = 1; x = if (true) {}; x + 1", "type mismatch: NULL + INTEGER"},
+		{"len(if (true) {})", "argument to `len` not supported, got NULL"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, errObj.Message)
+		}
+	}
+
+	testNullObject(t, testEval("puts(if (true) {})"))
+	testBooleanObject(t, testEval("let x = match (1) { 1 => {} }; x == 1"), false)
+
+	evaluated := testEval("let f = fn() {}; [f()]")
+	arr, ok := evaluated.(*object.Array)
+	if !ok || len(arr.Elements) != 1 {
+		t.Fatalf("object is not Array with 1 element. got=%T (%+v)", evaluated, evaluated)
+	}
+	testNullObject(t, arr.Elements[0])
+}
+
+func TestLetStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let a = 5; a;", 5},
+		{"let a = 5 * 5; a;", 25},
+		{"let a = 5; let b = a; b;", 5},
+		{"let a = 5; let b = a; let c = a + b + 5; c;", 15},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestUnboundIdentifier(t *testing.T) {
+	evaluated := testEval("foobar")
+
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+	}
+	if errObj.Message != "identifier not found: foobar" {
+		t.Errorf("wrong error message. expected=%q, got=%q",
+			"identifier not found: foobar", errObj.Message)
+	}
+}
+
+func TestFunctionObject(t *testing.T) {
+	input := "fn(x) { x + 2; };"
+
+	evaluated := testEval(input)
+	fn, ok := evaluated.(*object.Function)
+	if !ok {
+		t.Fatalf("object is not Function. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	if len(fn.Parameters) != 1 {
+		t.Fatalf("function has wrong parameters. Parameters=%+v", fn.Parameters)
+	}
+
+	if fn.Parameters[0].String() != "x" {
+		t.Fatalf("parameter is not 'x'. got=%q", fn.Parameters[0])
+	}
+
+	expectedBody := "(x + 2)"
+
+	if fn.Body.String() != expectedBody {
+		t.Fatalf("body is not %q. got=%q", expectedBody, fn.Body.String())
+	}
+}
+
+func TestFunctionApplication(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let identity = fn(x) { x; }; identity(5);", 5},
+		{"let double = fn(x) { x * 2; }; double(5);", 10},
+		{"let add = fn(x, y) { x + y; }; add(5, 5);", 10},
+		{"let add = fn(x, y) { x + y; }; add(5 + 5, add(5, 5));", 20},
+		{"fn(x) { x; }(5)", 5},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestClosures(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let adder = fn(x) { fn(y) { x + y } }; adder(2)(3)", 5},
+		{`let newAdder = fn(x) { fn(y) { x + y }; };
+let addTwo = newAdder(2);
+addTwo(2);`, 4},
+		{`let add = fn(a, b) { a + b };
+let applyFunc = fn(a, b, func) { func(a, b) };
+applyFunc(2, 2, add);`, 4},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestReturnStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"return 10;", 10},
+		{"return 10; 9;", 10},
+		{"return 2 * 5; 9;", 10},
+		{"9; return 2 * 5; 9;", 10},
+		{`
+if (10 > 1) {
+	if (10 > 1) {
+		return 10;
+	}
+
+	return 1;
+}
+`, 10},
+		{"if (true) { if (true) { return 10; } return 1; }", 10},
+		{"let f = fn(x) { return x; x + 10; }; f(10);", 10},
+		{"let f = fn(x) { let result = x + 10; return result; return 10; }; f(10);", 20},
+		{"let f = fn() { return 1; }; f(); 5;", 5},
+		//出现在表达式中的return同样会结束整个函数
+		{"let f = fn() { let x = if (true) { return 5; }; 10 }; f()", 5},
+		{"let f = fn() { const x = if (true) { return 5; }; 10 }; f()", 5},
+		{"fn() { 1 + if (true) { return 5; } }()", 5},
+		{"fn() { -if (true) { return 5; } }()", 5},
+		{"fn() { len(if (true) { return 5; }); 10 }()", 5},
+		{"fn() { [1, if (true) { return 5; }]; 10 }()", 5},
+		{`fn() { {"k": if (true) { return 5; }}; 10 }()`, 5},
+		{"fn() { [1][if (true) { return 5; }]; 10 }()", 5},
+		{"fn() { let x = 1; x = if (true) { return 5; }; 10 }()", 5},
+		{"fn() { if (if (true) { return 5; }) { 1 }; 10 }()", 5},
+		{"fn() { (if (true) { return 5; }) ? 1 : 2; 10 }()", 5},
+		{"fn() { (if (true) { return 5; }) && 1; 10 }()", 5},
+		{"fn() { match (if (true) { return 5; }) { _ => 1 }; 10 }()", 5},
+		{"fn() { for (x in if (true) { return 5; }) { 1 }; 10 }()", 5},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestErrorHandling(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"5 + true;", "type mismatch: INTEGER + BOOLEAN"},
+		{"5 + true; 5;", "type mismatch: INTEGER + BOOLEAN"},
+		{"-true", "unknown operator: -BOOLEAN"},
+		{"true + false;", "unknown operator: BOOLEAN + BOOLEAN"},
+		{"5; true + false; 5", "unknown operator: BOOLEAN + BOOLEAN"},
+		{"if (10 > 1) { true + false; }", "unknown operator: BOOLEAN + BOOLEAN"},
+		{`
+if (10 > 1) {
+	if (10 > 1) {
+		return true + false;
+	}
+
+	return 1;
+}
+`, "unknown operator: BOOLEAN + BOOLEAN"},
+		{"if (-true) { 1 }", "unknown operator: -BOOLEAN"},
+		{"-(1 + true) + 2", "type mismatch: INTEGER + BOOLEAN"},
+		{"let f = fn(x) { x + true }; f(1); 5", "type mismatch: INTEGER + BOOLEAN"},
+		{"let f = fn(x) { x }; f(-true)", "unknown operator: -BOOLEAN"},
+		{"let x = 1; x(2)", "not a function: INTEGER"},
+		{"let a = -false; 1", "unknown operator: -BOOLEAN"},
+		{"return -false; 1", "unknown operator: -BOOLEAN"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message. expected=%q, got=%q",
+				tt.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func TestDivisionByZero(t *testing.T) {
+	tests := []string{
+		"1 / 0",
+		"let x = 0; 10 / x",
+		"let f = fn(x) { 100 / x }; f(0); 1",
+	}
+
+	for _, input := range tests {
+		evaluated := testEval(input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != "division by zero" {
+			t.Errorf("wrong error message. got=%q", errObj.Message)
+		}
+	}
+}
+
+func TestInvalidShiftAndModulo(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"1 % 0", "division by zero"},
+		{"1 << -1", "negative shift count: -1"},
+		{"1 >> -1", "negative shift count: -1"},
+		{"~true", "unknown operator: ~BOOLEAN"},
+		{"true & false", "unknown operator: BOOLEAN & BOOLEAN"},
+		{`"a" <= "b"`, "unknown operator: STRING <= STRING"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func TestIntegerOverflow(t *testing.T) {
+	tests := []struct {
+		input    string
+		wrapped  int64
+		errorMsg string
+		promoted string
+	}{
+		{"9223372036854775807 + 1", -9223372036854775808,
+			"integer overflow: 9223372036854775807 + 1", "9223372036854775808"},
+		{"-9223372036854775807 - 2", 9223372036854775807,
+			"integer overflow: -9223372036854775807 - 2", "-9223372036854775809"},
+		{"4611686018427387904 * 2", -9223372036854775808,
+			"integer overflow: 4611686018427387904 * 2", "9223372036854775808"},
+		{"let min = -9223372036854775807 - 1; min / -1", -9223372036854775808,
+			"integer overflow: -9223372036854775808 / -1", "9223372036854775808"},
+		{"let min = -9223372036854775807 - 1; -min", -9223372036854775808,
+			"integer overflow: -(-9223372036854775808)", "9223372036854775808"},
+		{"1 << 63", -9223372036854775808,
+			"integer overflow: 1 << 63", "9223372036854775808"},
+		{"3 << 64", 0,
+			"integer overflow: 3 << 64", "55340232221128654848"},
+		{"let inc = fn(x) { x + 1 }; inc(9223372036854775807)", -9223372036854775808, //函数体中同样使用最外层环境的设置
+			"integer overflow: 9223372036854775807 + 1", "9223372036854775808"},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEvalWithOverflow(tt.input, object.OverflowWrap), tt.wrapped)
+
+		evaluated := testEvalWithOverflow(tt.input, object.OverflowError)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+		} else if errObj.Message != tt.errorMsg {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.errorMsg, errObj.Message)
+		}
+
+		evaluated = testEvalWithOverflow(tt.input, object.OverflowPromote)
+		bigInt, ok := evaluated.(*object.BigInteger)
+		if !ok {
+			t.Errorf("object is not BigInteger. got=%T (%+v)", evaluated, evaluated)
+		} else if bigInt.Inspect() != tt.promoted {
+			t.Errorf("object has wrong value. got=%s, want=%s", bigInt.Inspect(), tt.promoted)
+		}
+	}
+}
+
+func TestBigIntegerArithmetic(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let big = 9223372036854775807 + 1; big - 1", int64(9223372036854775807)},
+		{"let big = 9223372036854775807 * 4; big / 4", int64(9223372036854775807)},
+		{"let big = 9223372036854775807 + 1; big > 9223372036854775807", true},
+		{"let big = 9223372036854775807 + 1; big == big + 0", true},
+		{"let big = 9223372036854775807 + 1; big != 1", true},
+		{"let big = 9223372036854775807 + 1; big % 10", int64(8)},
+		{"let big = 9223372036854775807 + 1; big >> 62", int64(2)},
+		{"let big = 9223372036854775807 + 1; big & 1", int64(0)},
+		{"let big = 9223372036854775807 + 1; ~big", nil},
+		{"let big = 9223372036854775807 + 1; ~~big == big", true},
+		{"let big = 9223372036854775807 + 1; big >= big && big <= big", true},
+		{"let big = 9223372036854775807 + 1; big * 0.5", 4611686018427387904.0},
+		{"let big = 9223372036854775807 + 1; big < 1e19", true},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEvalWithOverflow(tt.input, object.OverflowPromote)
+		switch expected := tt.expected.(type) {
+		case int64:
+			testIntegerObject(t, evaluated, expected)
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case float64:
+			testFloatObject(t, evaluated, expected)
+		}
+	}
+
+	evaluated := testEvalWithOverflow("let big = 9223372036854775807 + 1; big / 0", object.OverflowPromote)
+	if errObj, ok := evaluated.(*object.Error); !ok || errObj.Message != "division by zero" {
+		t.Errorf("expected division by zero error. got=%T(%+v)", evaluated, evaluated)
+	}
+}
+
+func TestFloatExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"3.5", 3.5},
+		{"-2.5", -2.5},
+		{"1.5 + 2.25", 3.75},
+		{"1 + 0.5", 1.5},
+		{"0.5 * 4", 2.0},
+		{"7 / 2.0", 3.5},
+		{"7.5 % 2", 1.5},
+		{"1e3 - 1", 999.0},
+		{"1 < 1.5", true},
+		{"2.0 == 2", true},
+		{"2 != 2.0", false},
+		{"2.5 >= 2.5", true},
+		{"0.1 + 0.2 > 0.3", true},
+		{"7 / 2", int64(3)}, //两侧都是整数时仍然是整数除法
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case float64:
+			testFloatObject(t, evaluated, expected)
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case int64:
+			testIntegerObject(t, evaluated, expected)
+		}
+	}
+
+	errorTests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"1.5 / 0", "division by zero"},
+		{"1 % 0.0", "division by zero"},
+		{"1.5 & 1", "unknown operator: FLOAT & INTEGER"},
+		{"~1.5", "unknown operator: ~FLOAT"},
+		{`1.5 + "a"`, "type mismatch: FLOAT + STRING"},
+	}
+
+	for _, tt := range errorTests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func testFloatObject(t *testing.T, obj object.Object, expected float64) bool {
+	result, ok := obj.(*object.Float)
+	if !ok {
+		t.Errorf("object is not Float. got %T (%+v)", obj, obj)
+		return false
+	}
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%g, want=%g", result.Value, expected)
+		return false
+	}
+	return true
+}
+
+func TestStringLiteral(t *testing.T) {
+	input := `"Hello World!"`
+
+	evaluated := testEval(input)
+	str, ok := evaluated.(*object.String)
+	if !ok {
+		t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	if str.Value != "Hello World!" {
+		t.Errorf("String has wrong value. got=%q", str.Value)
+	}
+}
+
+func TestStringConcatenation(t *testing.T) {
+	input := `"Hello" + " " + "World!"`
+
+	evaluated := testEval(input)
+	str, ok := evaluated.(*object.String)
+	if !ok {
+		t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	if str.Value != "Hello World!" {
+		t.Errorf("String has wrong value. got=%q", str.Value)
+	}
+}
+
+func TestStringComparison(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{`"a" == "a"`, true},
+		{`"a" == "b"`, false},
+		{`"a" != "b"`, true},
+		{`"a" != "a"`, false},
+		{`let s = "mon"; s + "key" == "monkey"`, true},
+	}
+
+	for _, tt := range tests {
+		testBooleanObject(t, testEval(tt.input), tt.expected)
+	}
+
+	evaluated := testEval(`"Hello" - "World"`)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+	}
+	if errObj.Message != "unknown operator: STRING - STRING" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+}
+
+func TestArrayLiterals(t *testing.T) {
+	input := "[1, 2 * 2, 3 + 3]"
+
+	evaluated := testEval(input)
+	result, ok := evaluated.(*object.Array)
+	if !ok {
+		t.Fatalf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	if len(result.Elements) != 3 {
+		t.Fatalf("array has wrong num of elements. got=%d", len(result.Elements))
+	}
+
+	testIntegerObject(t, result.Elements[0], 1)
+	testIntegerObject(t, result.Elements[1], 4)
+	testIntegerObject(t, result.Elements[2], 6)
+}
+
+func TestArrayIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"[1, 2, 3][0]", 1},
+		{"[1, 2, 3][1]", 2},
+		{"[1, 2, 3][2]", 3},
+		{"let i = 0; [1][i];", 1},
+		{"[1, 2, 3][1 + 1];", 3},
+		{"let myArray = [1, 2, 3]; myArray[2];", 3},
+		{"let myArray = [1, 2, 3]; myArray[0] + myArray[1] + myArray[2];", 6},
+		{"let myArray = [1, 2, 3]; let i = myArray[0]; myArray[i]", 2},
+		{"[1, 2 * 2, fn(x){x}(3)][1]", 4},
+		{"[1, 2, 3][3]", nil},
+		{"[1, 2, 3][-1]", nil},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+
+	errorTests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"1[0]", "index operator not supported: INTEGER"},
+		{`[1]["a"]`, "array index must be INTEGER, got STRING"},
+		{"[1][1.0]", "array index must be INTEGER, got FLOAT"},
+	}
+
+	for _, tt := range errorTests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func TestHashLiterals(t *testing.T) {
+	input := `let two = "two";
+	{
+		"one": 10 - 9,
+		two: 1 + 1,
+		"thr" + "ee": 6 / 2,
+		4: 4,
+		true: 5,
+		false: 6
+	}`
+
+	evaluated := testEval(input)
+	result, ok := evaluated.(*object.Hash)
+	if !ok {
+		t.Fatalf("Eval didn't return Hash. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	expected := map[object.HashKey]int64{
+		(&object.String{Value: "one"}).HashKey():   1,
+		(&object.String{Value: "two"}).HashKey():   2,
+		(&object.String{Value: "three"}).HashKey(): 3,
+		(&object.Integer{Value: 4}).HashKey():      4,
+		TRUE.HashKey():                             5,
+		FALSE.HashKey():                            6,
+	}
+
+	if len(result.Pairs) != len(expected) {
+		t.Fatalf("Hash has wrong num of pairs. got=%d", len(result.Pairs))
+	}
+
+	for expectedKey, expectedValue := range expected {
+		pair, ok := result.Pairs[expectedKey]
+		if !ok {
+			t.Errorf("no pair for given key in Pairs")
+		}
+
+		testIntegerObject(t, pair.Value, expectedValue)
+	}
+}
+
+func TestHashLiteralEvaluationOrder(t *testing.T) {
+	input := `
+let log = [];
+let note = fn(x) { log = push(log, x); x };
+{note("a"): note(1), note("b"): note(2), note("c"): note(3), note("d"): note(4)};
+log`
+	for i := 0; i < 10; i++ { //map的遍历顺序是随机的，多执行几次
+		evaluated := testEval(input)
+		if evaluated.Inspect() != "[a, 1, b, 2, c, 3, d, 4]" {
+			t.Fatalf("wrong evaluation order. got=%s", evaluated.Inspect())
+		}
+	}
+
+	for i := 0; i < 10; i++ { //输出按照键第一次出现的顺序
+		evaluated := testEval(`let h = {"b": 1, "a": 2, "c": 3, "d": 4}; h["e"] = 5; h["a"] = 0; h`)
+		if evaluated.Inspect() != "{b: 1, a: 0, c: 3, d: 4, e: 5}" {
+			t.Fatalf("wrong Inspect order. got=%s", evaluated.Inspect())
+		}
+	}
+
+	for i := 0; i < 10; i++ {
+		evaluated := testEval(`{"a": x, "b": y, "c": z}`)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok || errObj.Message != "identifier not found: x" {
+			t.Fatalf("expected the first key's error. got=%T(%+v)", evaluated, evaluated)
+		}
+	}
+}
+
+func TestHashIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`{"foo": 5}["foo"]`, 5},
+		{`{"foo": 5}["bar"]`, nil},
+		{`let key = "foo"; {"foo": 5}[key]`, 5},
+		{`{}["foo"]`, nil},
+		{`{5: 5}[5]`, 5},
+		{`{true: 5}[true]`, 5},
+		{`{false: 5}[false]`, 5},
+		{`{1: true, true: 2}[true]`, 2},
+		{`let h = {"name": "monkey", "age": 1}; h["age"]`, 1},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestUnhashableKeys(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{`{"name": "Monkey"}[fn(x) { x }];`, "unusable as hash key: FUNCTION"},
+		{`{fn(x) { x }: "Monkey"};`, "unusable as hash key: FUNCTION"},
+		{`{[1]: 1};`, "unusable as hash key: ARRAY"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func TestBuiltinFunctions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`len("")`, 0},
+		{`len("four")`, 4},
+		{`len("hello world")`, 11},
+		{`len("你好")`, 2},
+		{`len([1, 2, 3])`, 3},
+		{`len([])`, 0},
+		{`len({"a": 1, "b": 2})`, 2},
+		{`len(1)`, "argument to `len` not supported, got INTEGER"},
+		{`len("one", "two")`, "wrong number of arguments: want=1, got=2"},
+		{`first([1, 2, 3])`, 1},
+		{`first([])`, nil},
+		{`first(1)`, "argument to `first` must be ARRAY, got INTEGER"},
+		{`last([1, 2, 3])`, 3},
+		{`last([])`, nil},
+		{`last(1)`, "argument to `last` must be ARRAY, got INTEGER"},
+		{`rest([1, 2, 3])`, []int{2, 3}},
+		{`rest([1])`, []int{}},
+		{`rest([])`, nil},
+		{`push([], 1)`, []int{1}},
+		{`let a = [1]; push(a, 2); a`, []int{1}},
+		{`push(1, 1)`, "argument to `push` must be ARRAY, got INTEGER"},
+		{`push([])`, "wrong number of arguments: want=2, got=1"},
+		{`type(1)`, "INTEGER"},
+		{`type("a")`, "STRING"},
+		{`type(len)`, "BUILTIN"},
+		{`type()`, "wrong number of arguments: want=1, got=0"},
+		{`let len = fn(x) { 42 }; len("abc")`, 42},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case nil:
+			testNullObject(t, evaluated)
+		case string:
+			switch obj := evaluated.(type) {
+			case *object.Error:
+				if obj.Message != expected {
+					t.Errorf("wrong error message. expected=%q, got=%q", expected, obj.Message)
+				}
+			case *object.String:
+				if obj.Value != expected {
+					t.Errorf("String has wrong value. expected=%q, got=%q", expected, obj.Value)
+				}
+			default:
+				t.Errorf("object is not Error or String. got=%T (%+v)", evaluated, evaluated)
+			}
+		case []int:
+			array, ok := evaluated.(*object.Array)
+			if !ok {
+				t.Errorf("obj not Array. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+
+			if len(array.Elements) != len(expected) {
+				t.Errorf("wrong num of elements. want=%d, got=%d", len(expected), len(array.Elements))
+				continue
+			}
+
+			for i, expectedElem := range expected {
+				testIntegerObject(t, array.Elements[i], int64(expectedElem))
+			}
+		}
+	}
+}
+
+func TestErrorPositions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"5 + true", "ERROR: 1:1: type mismatch: INTEGER + BOOLEAN"},
+		{"let x = 1;\nlet y = x + foo;", "ERROR: 2:13: identifier not found: foo"},
+		{"let f = fn(x) {\n  x / 0\n};\nf(1)", "ERROR: 2:3: division by zero"},
+		{"let a = 1;\n  len(a)", "ERROR: 2:3: argument to `len` not supported, got INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			continue
+		}
+		if errObj.Inspect() != tt.expected {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expected, errObj.Inspect())
+		}
+	}
+}
+
+func TestUnicodeSource(t *testing.T) {
+	input := `let 问候 = fn(名字) { "你好，" + 名字 };
+问候("世界")`
+
+	evaluated := testEval(input)
+	str, ok := evaluated.(*object.String)
+	if !ok {
+		t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
+	}
+	if str.Value != "你好，世界" {
+		t.Errorf("String has wrong value. got=%q", str.Value)
+	}
+}
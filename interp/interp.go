@@ -0,0 +1,101 @@
+package interp
+
+import (
+	"monkey/diagnostic"
+	"monkey/evaluator"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+	"strings"
+)
+
+// HostFunc 是宿主程序注册给Monkey调用的Go函数，返回的error会被转换为Monkey的运行时错误
+type HostFunc func(args ...object.Object) (object.Object, error)
+
+// Interpreter 将词法分析、语法分析和求值串联起来，供Go程序嵌入使用。
+// 同一个Interpreter的多次EvalString共享同一个顶层环境
+type Interpreter struct {
+	env *object.Environment
+}
+
+func New() *Interpreter {
+	return &Interpreter{env: object.NewEnvironment()}
+}
+
+// Define 在顶层环境中绑定一个值，脚本中可以直接通过name访问
+func (i *Interpreter) Define(name string, v object.Object) {
+	i.env.Set(name, v)
+}
+
+// SetOverflowMode 设置整数运算溢出时的处理方式，只影响这个Interpreter，默认为object.OverflowWrap
+func (i *Interpreter) SetOverflowMode(mode object.OverflowMode) {
+	i.env.SetOverflowMode(mode)
+}
+
+// RegisterFunc 将Go函数注册为名为name的可调用对象。fn返回nil时脚本中得到null
+func (i *Interpreter) RegisterFunc(name string, fn HostFunc) {
+	i.Define(name, &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		result, err := fn(args...)
+		if err != nil {
+			return &object.Error{Message: err.Error()}
+		}
+		if result == nil {
+			return evaluator.NULL
+		}
+		return result
+	}})
+}
+
+// EvalString 解析并执行src，返回最后一条语句的值。
+// 语法错误返回*ParseError，运行时错误返回*RuntimeError
+func (i *Interpreter) EvalString(src string) (object.Object, error) {
+	return i.EvalFile("", src)
+}
+
+// EvalFile 与EvalString相同，filename会出现在错误的位置信息中，形如file:line:col
+func (i *Interpreter) EvalFile(filename string, src string) (object.Object, error) {
+	l := lexer.NewWithFilename(filename, src)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, &ParseError{Source: src, Errors: p.Errors()}
+	}
+
+	result := evaluator.Eval(program, i.env)
+	if errObj, ok := result.(*object.Error); ok {
+		return nil, &RuntimeError{Err: errObj}
+	}
+	if result == nil { //例如最后一条语句是let语句
+		return evaluator.NULL, nil
+	}
+	return result, nil
+}
+
+type ParseError struct {
+	Source string
+	Errors []diagnostic.Diagnostic
+}
+
+func (pe *ParseError) Error() string {
+	msgs := make([]string, len(pe.Errors))
+	for i, d := range pe.Errors {
+		msgs[i] = d.String()
+	}
+	return "parse errors: " + strings.Join(msgs, "; ")
+}
+
+// Render 输出带有源代码片段和^~~~标记的完整错误信息
+func (pe *ParseError) Render() string {
+	return diagnostic.RenderAll(pe.Source, pe.Errors)
+}
+
+type RuntimeError struct {
+	Err *object.Error
+}
+
+func (re *RuntimeError) Error() string {
+	if re.Err.Pos.IsValid() {
+		return re.Err.Pos.String() + ": " + re.Err.Message
+	}
+	return re.Err.Message
+}
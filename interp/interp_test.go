@@ -0,0 +1,135 @@
+package interp
+
+import (
+	"errors"
+	"monkey/object"
+	"strings"
+	"testing"
+)
+
+func TestEvalStringSharesEnvironment(t *testing.T) {
+	in := New()
+
+	if _, err := in.EvalString("let x = 5;"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	result, err := in.EvalString("x * 2")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	testIntegerObject(t, result, 10)
+}
+
+func TestDefine(t *testing.T) {
+	in := New()
+	in.Define("name", &object.String{Value: "monkey"})
+
+	result, err := in.EvalString(`"hello " + name`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	str, ok := result.(*object.String)
+	if !ok {
+		t.Fatalf("object is not String. got=%T (%+v)", result, result)
+	}
+	if str.Value != "hello monkey" {
+		t.Errorf("String has wrong value. got=%q", str.Value)
+	}
+}
+
+func TestRegisterFunc(t *testing.T) {
+	in := New()
+	in.RegisterFunc("double", func(args ...object.Object) (object.Object, error) {
+		if len(args) != 1 {
+			return nil, errors.New("double takes exactly one argument")
+		}
+		i, ok := args[0].(*object.Integer)
+		if !ok {
+			return nil, errors.New("double expects an INTEGER")
+		}
+		return &object.Integer{Value: i.Value * 2}, nil
+	})
+	in.RegisterFunc("noop", func(args ...object.Object) (object.Object, error) {
+		return nil, nil
+	})
+
+	result, err := in.EvalString("let f = fn(x) { double(x) + 1 }; f(20)")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	testIntegerObject(t, result, 41)
+
+	result, err = in.EvalString("noop()")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if result.Type() != object.NULL_OBJ {
+		t.Errorf("object is not NULL. got=%T (%+v)", result, result)
+	}
+
+	_, err = in.EvalString(`double("x")`)
+	var runtimeErr *RuntimeError
+	if !errors.As(err, &runtimeErr) {
+		t.Fatalf("error is not *RuntimeError. got=%T (%+v)", err, err)
+	}
+	if runtimeErr.Error() != "1:1: double expects an INTEGER" {
+		t.Errorf("wrong error message. got=%q", runtimeErr.Error())
+	}
+}
+
+func TestSetOverflowMode(t *testing.T) {
+	checked := New()
+	checked.SetOverflowMode(object.OverflowError)
+	if _, err := checked.EvalString("9223372036854775807 + 1"); err == nil || err.Error() != "1:1: integer overflow: 9223372036854775807 + 1" {
+		t.Errorf("expected integer overflow error. got=%v", err)
+	}
+
+	result, err := New().EvalString("9223372036854775807 + 1") //其他Interpreter不受影响
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	testIntegerObject(t, result, -9223372036854775808)
+}
+
+func TestEvalFileErrorPositions(t *testing.T) {
+	_, err := New().EvalFile("main.mk", "let x = 1;\nx + true")
+	if err == nil || err.Error() != "main.mk:2:1: type mismatch: INTEGER + BOOLEAN" {
+		t.Errorf("wrong runtime error. got=%v", err)
+	}
+
+	_, err = New().EvalFile("main.mk", "let = 5;")
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("error is not *ParseError. got=%T (%+v)", err, err)
+	}
+	if !strings.HasPrefix(parseErr.Errors[0].String(), "main.mk:1:5: ") {
+		t.Errorf("parse error has no file name. got=%q", parseErr.Errors[0].String())
+	}
+}
+
+func TestEvalStringParseError(t *testing.T) {
+	_, err := New().EvalString("let = 5;")
+
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("error is not *ParseError. got=%T (%+v)", err, err)
+	}
+	if len(parseErr.Errors) == 0 {
+		t.Errorf("ParseError has no messages")
+	}
+}
+
+func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
+	result, ok := obj.(*object.Integer)
+	if !ok {
+		t.Errorf("object is not Integer. got %T (%+v)", obj, obj)
+		return false
+	}
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%d, want=%d", result.Value, expected)
+		return false
+	}
+	return true
+}
@@ -2,67 +2,125 @@ package lexer
 
 import (
 	"fmt"
+	"io"
+	"monkey/diagnostic"
 	"monkey/token"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 type Lexer struct {
 	input        string
-	position     int //这个是当前的字符
-	readPosition int //这个代表向后看字符
-	ch           byte
+	filename     string
+	position     int  //当前字符的字节偏移
+	readPosition int  //向后看字符的字节偏移
+	ch           rune //当前字符，输入按照UTF-8解码
+	line         int  //当前字符所在的行，从1开始
+	column       int  //当前字符所在的列，从1开始
+
+	preserveComments bool      //为true时注释会作为COMMENT词法单元返回，而不是被直接跳过
+	traceOut         io.Writer //为nil时不输出词法单元
+	errors           []diagnostic.Diagnostic
+}
+
+// Option 用于在创建词法分析器时进行配置
+type Option func(*Lexer)
+
+// WithTrace 让词法分析器将产生的每一个词法单元输出到w，格式为：位置 类型 字面量
+func WithTrace(w io.Writer) Option {
+	return func(l *Lexer) {
+		l.traceOut = w
+	}
 }
 
-func New(input string) *Lexer { //返回的是一个指针类型
-	l := &Lexer{input: input} //直接获取初始化的指针变量地址
-	l.readChar()              //在新建的时候直接对其初始化
+// WithComments 让词法分析器保留注释，供格式化工具等需要还原注释的场景使用
+func WithComments() Option {
+	return func(l *Lexer) {
+		l.preserveComments = true
+	}
+}
+
+func New(input string, opts ...Option) *Lexer { //返回的是一个指针类型
+	return NewWithFilename("", input, opts...)
+}
+
+// NewWithFilename 创建一个词法分析器，filename会被记录在每个词法单元的位置信息中
+func NewWithFilename(filename string, input string, opts ...Option) *Lexer {
+	l := &Lexer{input: input, filename: filename, line: 1} //直接获取初始化的指针变量地址
+	for _, opt := range opts {
+		opt(l)
+	}
+	l.readChar() //在新建的时候直接对其初始化
 	return l
 }
 
-/**
-辅助函数，用于将移动指针的这种原子操作抽象出来。
-*/
+// Errors 返回词法分析过程中产生的错误，例如未闭合的块注释
+func (l *Lexer) Errors() []diagnostic.Diagnostic {
+	return l.errors
+}
+
+// 辅助函数，用于将移动指针的这种原子操作抽象出来。
 func (l *Lexer) readChar() {
+	if l.ch == '\n' { //越过换行符之后进入下一行
+		l.line += 1
+		l.column = 1
+	} else {
+		l.column += 1
+	}
+	width := 0
 	if l.readPosition >= len(l.input) {
 		l.ch = 0 //表明已经到了字符串结尾
 	} else {
-		l.ch = l.input[l.readPosition]
+		l.ch, width = utf8.DecodeRuneInString(l.input[l.readPosition:]) //非法的UTF-8序列被解码为utf8.RuneError
 	}
 	l.position = l.readPosition
-	l.readPosition += 1 //结束，保证readPosition始终在position前面一个字符
+	l.readPosition += width //结束，保证readPosition始终指向下一个字符的第一个字节
 }
 
-func (l *Lexer) peerChar() byte {
+func (l *Lexer) peerChar() rune {
 	if l.readPosition >= len(l.input) {
 		return 0
 	} else {
-		return l.input[l.readPosition]
+		r, _ := utf8.DecodeRuneInString(l.input[l.readPosition:])
+		return r
 	}
 }
-func (l *Lexer) unreadChar() { //回退向前看字符
-	if l.position == 0 {
-		l.ch = 0
-		return
+
+func (l *Lexer) NextToken() token.Token {
+	tok := l.readToken()
+	if l.traceOut != nil {
+		io.WriteString(l.traceOut, fmt.Sprintf("%s\t%s\t%q\n", tok.Pos, tok.Type, tok.Literal))
 	}
-	l.ch = l.input[l.readPosition]
-	l.readPosition = l.position
-	l.position -= 1
+	return tok
 }
 
-func (l *Lexer) NextToken() token.Token {
-	for l.ch == ' ' || l.ch == '\n' || l.ch == '\b' || l.ch == '\t' || l.ch == '\r' { //吸收空字符
-		l.readChar()
+func (l *Lexer) readToken() token.Token {
+	for {
+		for l.ch == ' ' || l.ch == '\n' || l.ch == '\b' || l.ch == '\t' || l.ch == '\r' { //吸收空字符
+			l.readChar()
+		}
+		if l.ch != '/' || (l.peerChar() != '/' && l.peerChar() != '*') {
+			break
+		}
+		pos := l.pos()
+		comment := l.readComment()
+		if l.preserveComments {
+			return token.Token{Type: token.COMMENT, Literal: comment, Pos: pos, End: l.pos()}
+		}
 	}
-	fmt.Print(l.ch)
+	pos := l.pos()
 	var tok token.Token
 	switch l.ch {
 	case '=':
-		tok = newToken(token.ASSIGN, l.ch)
-		if l.input[l.readPosition] == '=' { //如果下一个字符依然是=
-			tok = token.Token{Type: token.EQ, Literal: "=="}
-			l.readChar()
-		}
+		tok = l.readTwoCharToken(token.ASSIGN, map[rune]token.TokenType{'=': token.EQ, '>': token.ARROW})
 	case ';':
 		tok = newToken(token.SEMICOLON, l.ch)
+	case ':':
+		tok = newToken(token.COLON, l.ch)
+	case '?':
+		tok = newToken(token.QUESTION, l.ch)
 	case '(':
 		tok = newToken(token.LPAREN, l.ch)
 	case ')':
@@ -71,72 +129,280 @@ func (l *Lexer) NextToken() token.Token {
 		tok = newToken(token.LBRACE, l.ch)
 	case '}':
 		tok = newToken(token.RBRACE, l.ch)
+	case '[':
+		tok = newToken(token.LBRACKET, l.ch)
+	case ']':
+		tok = newToken(token.RBRACKET, l.ch)
 	case ',':
 		tok = newToken(token.COMMA, l.ch)
 	case '+':
-		tok = newToken(token.PLUS, l.ch)
+		tok = l.readTwoCharToken(token.PLUS, map[rune]token.TokenType{'=': token.PLUS_ASSIGN})
 	case '-':
-		tok = newToken(token.MINUS, l.ch)
+		tok = l.readTwoCharToken(token.MINUS, map[rune]token.TokenType{'=': token.MINUS_ASSIGN})
 	case '/':
-		tok = newToken(token.SLASH, l.ch)
+		tok = l.readTwoCharToken(token.SLASH, map[rune]token.TokenType{'=': token.SLASH_ASSIGN})
 	case '*':
-		tok = newToken(token.ASTERISK, l.ch)
+		tok = l.readTwoCharToken(token.ASTERISK, map[rune]token.TokenType{'=': token.ASTERISK_ASSIGN})
+	case '%':
+		tok = newToken(token.PERCENT, l.ch)
 	case '!':
 		tok = newToken(token.BANG, l.ch)
-		if l.input[l.readPosition] == '=' { //如果下一个字符是=
+		if l.peerChar() == '=' { //如果下一个字符是=
 			tok = token.Token{Type: token.NOT_EQ, Literal: "!="}
 			l.readChar()
 		}
 	case '<':
-		tok = newToken(token.LT, l.ch)
+		tok = l.readTwoCharToken(token.LT, map[rune]token.TokenType{'=': token.LT_EQ, '<': token.SHL})
 	case '>':
-		tok = newToken(token.GT, l.ch)
+		tok = l.readTwoCharToken(token.GT, map[rune]token.TokenType{'=': token.GT_EQ, '>': token.SHR})
+	case '&':
+		tok = l.readTwoCharToken(token.BIT_AND, map[rune]token.TokenType{'&': token.AND})
+	case '|':
+		tok = l.readTwoCharToken(token.BIT_OR, map[rune]token.TokenType{'|': token.OR})
+	case '^':
+		tok = newToken(token.BIT_XOR, l.ch)
+	case '~':
+		tok = newToken(token.TILDE, l.ch)
+	case '"':
+		str, ok := l.readString()
+		if ok {
+			tok = token.Token{Type: token.STRING, Literal: str}
+		} else { //未闭合的字符串或者非法的转义序列
+			tok = token.Token{Type: token.ILLEGAL, Literal: str}
+		}
 
 	case 0: //0表示字符串的末尾
 		tok.Literal = ""
 		tok.Type = token.EOF
+		tok.Pos, tok.End = pos, pos
+		return tok //停留在输入末尾，保证多次读取EOF时位置不变
 	default:
 		if isLetter(l.ch) { //如果是英文字符开头
 			tok.Literal = l.readIdentifier()
 			tok.Type = token.LookupIdent(tok.Literal)
+			tok.Pos, tok.End = pos, l.pos()
 			return tok
-		} else if isNumber(l.ch) { //如果是number开头的，默认其为INT类型
-			tok.Literal = l.readNumber()
-			tok.Type = token.INT
+		} else if isNumber(l.ch) { //如果是number开头的，根据是否有小数部分或者指数部分区分INT和FLOAT
+			tok.Literal, tok.Type = l.readNumber()
+			tok.Pos, tok.End = pos, l.pos()
 			return tok
 		} else {
 			tok = newToken(token.ILLEGAL, l.ch)
 		}
 	}
 	l.readChar()
+	tok.Pos, tok.End = pos, l.pos()
 	return tok
 }
 
-func newToken(tokenType token.TokenType, ch byte) token.Token {
+// readTwoCharToken 根据下一个字符判断是否组成双字符运算符，不组成时返回单字符的single。
+// 组成双字符运算符时会多读取一个字符，使l.ch停留在运算符的第二个字符上
+func (l *Lexer) readTwoCharToken(single token.TokenType, pairs map[rune]token.TokenType) token.Token {
+	if tokenType, ok := pairs[l.peerChar()]; ok {
+		ch := l.ch
+		l.readChar()
+		return token.Token{Type: tokenType, Literal: string(ch) + string(l.ch)}
+	}
+	return newToken(single, l.ch)
+}
+
+// pos 返回当前字符l.ch的位置
+func (l *Lexer) pos() token.Position {
+	return token.Position{Filename: l.filename, Offset: l.position, Line: l.line, Column: l.column}
+}
+
+// posAfter 返回当前字符之后的位置，用于标记以当前字符结尾的错误范围
+func (l *Lexer) posAfter() token.Position {
+	if l.ch == '\n' {
+		return token.Position{Filename: l.filename, Offset: l.readPosition, Line: l.line + 1, Column: 1}
+	}
+	return token.Position{Filename: l.filename, Offset: l.readPosition, Line: l.line, Column: l.column + 1}
+}
+
+// errorf 记录一条覆盖start到end的词法错误，返回值可以用于补充Hint
+func (l *Lexer) errorf(start, end token.Position, format string, a ...interface{}) *diagnostic.Diagnostic {
+	l.errors = append(l.errors, diagnostic.Errorf(start, end, format, a...))
+	return &l.errors[len(l.errors)-1]
+}
+
+func newToken(tokenType token.TokenType, ch rune) token.Token {
 	return token.Token{Type: tokenType, Literal: string(ch)}
 }
 
-func isLetter(ch byte) bool {
-	return ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch == '_'
+func isLetter(ch rune) bool {
+	return ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch == '_' || ch >= utf8.RuneSelf && unicode.IsLetter(ch)
 }
+
+// readIdentifier 读取一个标识符，标识符以字母开头，之后可以包含数字
 func (l *Lexer) readIdentifier() string {
 	position := l.position
-	for isLetter(l.ch) {
+	for isLetter(l.ch) || isNumber(l.ch) {
 		l.readChar()
 	}
 	return l.input[position:l.position]
 }
-func isNumber(ch byte) bool {
+func isNumber(ch rune) bool {
 	if ch >= '0' && ch <= '9' {
 		return true
 	}
 	return false
 }
 
-func (l *Lexer) readNumber() string {
+// readNumber 读取整数或者浮点数字面量，数字之间允许使用_分隔。
+// 这里只负责切分出完整的字面量，字面量是否合法（例如0b12、1__0）由解析器调用strconv时检查
+func (l *Lexer) readNumber() (string, token.TokenType) {
 	position := l.position
-	for isNumber(l.ch) {
+	if l.ch == '0' && strings.ContainsRune("xXoObB", l.peerChar()) { //带进制前缀的整数
+		l.readChar()
 		l.readChar()
+		for isLetter(l.ch) || isNumber(l.ch) { //十六进制的a-f属于字母，_也包含在isLetter中
+			l.readChar()
+		}
+		return l.input[position:l.position], token.INT
 	}
-	return l.input[position:l.position]
+
+	tokenType := token.TokenType(token.INT)
+	l.readDigits()
+	if l.ch == '.' && isNumber(l.peerChar()) { //小数点之后必须紧跟数字
+		tokenType = token.FLOAT
+		l.readChar()
+		l.readDigits()
+	}
+	if l.ch == 'e' || l.ch == 'E' {
+		tokenType = token.FLOAT
+		l.readChar()
+		if l.ch == '+' || l.ch == '-' {
+			l.readChar()
+		}
+		l.readDigits()
+	}
+	return l.input[position:l.position], tokenType
+}
+
+func (l *Lexer) readDigits() {
+	for isNumber(l.ch) || l.ch == '_' {
+		l.readChar()
+	}
+}
+
+// readString 读取双引号包围的字符串并处理其中的转义序列，结束时l.ch停留在右引号上。
+// 当字符串未闭合或者包含非法的转义序列时，ok为false，返回值为读取到的原始文本，具体的错误记录在l.errors中。
+// 遇到非法的转义序列之后会继续读取到右引号为止，避免字符串的剩余部分被当作代码继续分析
+func (l *Lexer) readString() (string, bool) {
+	start := l.position
+	open, openEnd := l.pos(), l.posAfter() //未闭合时标记左引号
+	valid := true
+	var out strings.Builder
+	for {
+		l.readChar()
+		switch l.ch {
+		case '"':
+			if !valid {
+				return l.input[start:l.position], false
+			}
+			return out.String(), true
+		case 0:
+			l.errorf(open, openEnd, "unterminated string literal")
+			return l.input[start:l.position], false
+		case '\\':
+			escape := l.pos()
+			l.readChar()
+			switch l.ch {
+			case 'n':
+				out.WriteByte('\n')
+			case 't':
+				out.WriteByte('\t')
+			case '"':
+				out.WriteByte('"')
+			case '\\':
+				out.WriteByte('\\')
+			case 'u':
+				r, ok := l.readUnicodeEscape(escape)
+				if !ok {
+					valid = false
+				}
+				out.WriteRune(r)
+			case 0:
+				l.errorf(open, openEnd, "unterminated string literal")
+				return l.input[start:l.position], false
+			default:
+				d := l.errorf(escape, l.posAfter(), "invalid escape sequence \\%c", l.ch)
+				d.Hint = `supported escapes are \n, \t, \", \\ and \u{...}`
+				valid = false
+			}
+		default:
+			out.WriteRune(l.ch)
+		}
+	}
+}
+
+// readUnicodeEscape 解析\u{...}形式的转义，escape为反斜杠的位置。进入时l.ch为'u'，成功时l.ch停留在'}'上
+func (l *Lexer) readUnicodeEscape(escape token.Position) (rune, bool) {
+	if l.peerChar() != '{' {
+		d := l.errorf(escape, l.posAfter(), "invalid escape sequence \\u")
+		d.Hint = `unicode escapes are written as \u{1F600}`
+		return 0, false
+	}
+	l.readChar()
+	position := l.position + 1
+	for isHexDigit(l.peerChar()) {
+		l.readChar()
+	}
+	if l.peerChar() != '}' || l.position+1 == position { //缺少右花括号或者没有任何十六进制数字
+		d := l.errorf(escape, l.posAfter(), "invalid escape sequence %s", l.input[escape.Offset:l.position+1])
+		d.Hint = `unicode escapes are written as \u{1F600}`
+		return 0, false
+	}
+	l.readChar()
+	code, err := strconv.ParseUint(l.input[position:l.position], 16, 32)
+	if err != nil || code > 0x10FFFF || (code >= 0xD800 && code <= 0xDFFF) { //超出Unicode范围或者是代理区
+		l.errorf(escape, l.posAfter(), "invalid code point in %s", l.input[escape.Offset:l.position+1])
+		return 0, false
+	}
+	return rune(code), true
+}
+
+func isHexDigit(ch rune) bool {
+	return isNumber(ch) || ch >= 'a' && ch <= 'f' || ch >= 'A' && ch <= 'F'
+}
+
+// readComment 读取一条//行注释或者/* */块注释，块注释允许嵌套。
+// 进入时l.ch为'/'，结束时l.ch停留在注释之后的第一个字符上，返回包括注释符号在内的完整注释
+func (l *Lexer) readComment() string {
+	start := l.position
+	if l.peerChar() == '/' {
+		for l.ch != '\n' && l.ch != 0 {
+			l.readChar()
+		}
+		return strings.TrimRight(l.input[start:l.position], "\r")
+	}
+
+	open := l.pos()
+	l.readChar()
+	l.readChar()
+	openEnd := l.pos() //出错时标记开头的/*
+	depth, nested := 1, false
+	for depth > 0 {
+		switch {
+		case l.ch == 0:
+			d := diagnostic.Errorf(open, openEnd, "unterminated block comment")
+			if nested { //内层注释的*/不会结束外层注释，很容易引起误解
+				d.Hint = fmt.Sprintf("block comments nest; %d more */ needed", depth)
+			}
+			l.errors = append(l.errors, d)
+			return l.input[start:l.position]
+		case l.ch == '/' && l.peerChar() == '*':
+			depth++
+			nested = true
+			l.readChar()
+			l.readChar()
+		case l.ch == '*' && l.peerChar() == '/':
+			depth--
+			l.readChar()
+			l.readChar()
+		default:
+			l.readChar()
+		}
+	}
+	return l.input[start:l.position]
 }
@@ -1,6 +1,7 @@
 package lexer
 
 import (
+	"bytes"
 	"monkey/token"
 	"testing"
 )
@@ -112,7 +113,7 @@ let add = fn(x, y) {
 };
 
 let result = add(five, ten);
-!-/*5;
+!-/ *5;
 5 < 10 > 5;
 
 if (5 < 10) {
@@ -221,3 +222,584 @@ if (5 < 10) {
 		}
 	}
 }
+
+func TestOperatorTokens(t *testing.T) {
+	input := `a <= b >= c % d && e || f & g | h ^ i << j >> k ~l < m > n &&& o ? p : q`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENT, "a"},
+		{token.LT_EQ, "<="},
+		{token.IDENT, "b"},
+		{token.GT_EQ, ">="},
+		{token.IDENT, "c"},
+		{token.PERCENT, "%"},
+		{token.IDENT, "d"},
+		{token.AND, "&&"},
+		{token.IDENT, "e"},
+		{token.OR, "||"},
+		{token.IDENT, "f"},
+		{token.BIT_AND, "&"},
+		{token.IDENT, "g"},
+		{token.BIT_OR, "|"},
+		{token.IDENT, "h"},
+		{token.BIT_XOR, "^"},
+		{token.IDENT, "i"},
+		{token.SHL, "<<"},
+		{token.IDENT, "j"},
+		{token.SHR, ">>"},
+		{token.IDENT, "k"},
+		{token.TILDE, "~"},
+		{token.IDENT, "l"},
+		{token.LT, "<"},
+		{token.IDENT, "m"},
+		{token.GT, ">"},
+		{token.IDENT, "n"},
+		{token.AND, "&&"},
+		{token.BIT_AND, "&"},
+		{token.IDENT, "o"},
+		{token.QUESTION, "?"},
+		{token.IDENT, "p"},
+		{token.COLON, ":"},
+		{token.IDENT, "q"},
+		{token.EOF, ""},
+	}
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong, expected=%q, actual=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong, expected=%q, actual=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestAssignmentTokens(t *testing.T) {
+	input := `x = 1; x += 2; x -= 3; x *= 4; x /= 5; x == 6`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.INT, "1"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.PLUS_ASSIGN, "+="},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.MINUS_ASSIGN, "-="},
+		{token.INT, "3"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.ASTERISK_ASSIGN, "*="},
+		{token.INT, "4"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.SLASH_ASSIGN, "/="},
+		{token.INT, "5"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.EQ, "=="},
+		{token.INT, "6"},
+		{token.EOF, ""},
+	}
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong, expected=%q, actual=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong, expected=%q, actual=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestMatchTokens(t *testing.T) {
+	input := `match (v) { 1 => a, _ => b }`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.MATCH, "match"},
+		{token.LPAREN, "("},
+		{token.IDENT, "v"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.INT, "1"},
+		{token.ARROW, "=>"},
+		{token.IDENT, "a"},
+		{token.COMMA, ","},
+		{token.IDENT, "_"},
+		{token.ARROW, "=>"},
+		{token.IDENT, "b"},
+		{token.RBRACE, "}"},
+		{token.EOF, ""},
+	}
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong, expected=%q, actual=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong, expected=%q, actual=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestStringToken(t *testing.T) {
+	input := `"foobar" "foo bar" "line\nnext\ttab" "say \"hi\"" "back\\slash" "\u{4F60}\u{597D}" ""`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.STRING, "foobar"},
+		{token.STRING, "foo bar"},
+		{token.STRING, "line\nnext\ttab"},
+		{token.STRING, `say "hi"`},
+		{token.STRING, `back\slash`},
+		{token.STRING, "你好"},
+		{token.STRING, ""},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong, expected=%q, actual=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong, expected=%q, actual=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestIllegalString(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"unterminated`, "1:1: unterminated string literal"},
+		{`"bad \q escape"`, `1:6: invalid escape sequence \q`},
+		{`"\u{110000}"`, `1:2: invalid code point in \u{110000}`},
+		{`"\u{D800}"`, `1:2: invalid code point in \u{D800}`},
+		{`"\u{zz}"`, `1:2: invalid escape sequence \u{`},
+		{`"\u0041"`, `1:2: invalid escape sequence \u`},
+		{`"ends with \`, "1:1: unterminated string literal"},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		tok := l.NextToken()
+		if tok.Type != token.ILLEGAL {
+			t.Errorf("input %q - tokentype wrong, expected=%q, actual=%q", tt.input, token.ILLEGAL, tok.Type)
+		}
+		if next := l.NextToken(); next.Type != token.EOF { //字符串中剩余的部分不能被当作代码
+			t.Errorf("input %q - expected EOF after string, got=%q", tt.input, next.Type)
+		}
+		errors := l.Errors()
+		if len(errors) != 1 {
+			t.Errorf("input %q - wrong number of errors. got=%d", tt.input, len(errors))
+			continue
+		}
+		if errors[0].String() != tt.expected {
+			t.Errorf("input %q - wrong error. expected=%q, got=%q", tt.input, tt.expected, errors[0].String())
+		}
+	}
+}
+
+func TestBracketTokens(t *testing.T) {
+	input := `[1, 2][0];`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LBRACKET, "["},
+		{token.INT, "1"},
+		{token.COMMA, ","},
+		{token.INT, "2"},
+		{token.RBRACKET, "]"},
+		{token.LBRACKET, "["},
+		{token.INT, "0"},
+		{token.RBRACKET, "]"},
+		{token.SEMICOLON, ";"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong, expected=%q, actual=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong, expected=%q, actual=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestTokenPositions(t *testing.T) {
+	input := `let x = 5;
+if (x == "ab") {
+  x
+}`
+
+	tests := []struct {
+		expectedType token.TokenType
+		line, column int
+		offset       int
+		endColumn    int
+	}{
+		{token.LET, 1, 1, 0, 4},
+		{token.IDENT, 1, 5, 4, 6},
+		{token.ASSIGN, 1, 7, 6, 8},
+		{token.INT, 1, 9, 8, 10},
+		{token.SEMICOLON, 1, 10, 9, 11},
+		{token.IF, 2, 1, 11, 3},
+		{token.LPAREN, 2, 4, 14, 5},
+		{token.IDENT, 2, 5, 15, 6},
+		{token.EQ, 2, 7, 17, 9},
+		{token.STRING, 2, 10, 20, 14},
+		{token.RPAREN, 2, 14, 24, 15},
+		{token.LBRACE, 2, 16, 26, 17},
+		{token.IDENT, 3, 3, 30, 4},
+		{token.RBRACE, 4, 1, 32, 2},
+		{token.EOF, 4, 2, 33, 2},
+	}
+
+	l := NewWithFilename("test.monkey", input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong, expected=%q, actual=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Pos.Line != tt.line || tok.Pos.Column != tt.column || tok.Pos.Offset != tt.offset {
+			t.Fatalf("tests[%d] - position wrong, expected=%d:%d (offset %d), actual=%d:%d (offset %d)",
+				i, tt.line, tt.column, tt.offset, tok.Pos.Line, tok.Pos.Column, tok.Pos.Offset)
+		}
+		if tok.End.Line != tt.line || tok.End.Column != tt.endColumn {
+			t.Fatalf("tests[%d] - end position wrong, expected=%d:%d, actual=%d:%d",
+				i, tt.line, tt.endColumn, tok.End.Line, tok.End.Column)
+		}
+		if tok.Pos.Filename != "test.monkey" {
+			t.Fatalf("tests[%d] - filename wrong, expected=%q, actual=%q", i, "test.monkey", tok.Pos.Filename)
+		}
+	}
+}
+
+func TestIdentifiersWithDigits(t *testing.T) {
+	input := `x1 abc123 a1b2 1x while1 for const in import null break continue`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENT, "x1"},
+		{token.IDENT, "abc123"},
+		{token.IDENT, "a1b2"},
+		{token.INT, "1"}, //标识符不能以数字开头
+		{token.IDENT, "x"},
+		{token.IDENT, "while1"},
+		{token.FOR, "for"},
+		{token.CONST, "const"},
+		{token.IN, "in"},
+		{token.IMPORT, "import"},
+		{token.NULL, "null"},
+		{token.BREAK, "break"},
+		{token.CONTINUE, "continue"},
+		{token.EOF, ""},
+	}
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong, expected=%q, actual=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong, expected=%q, actual=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestUnicodeIdentifiers(t *testing.T) {
+	input := `let 名字 = "猴子";
+let café = 名字 + "!";
+λ`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		line, column    int
+	}{
+		{token.LET, "let", 1, 1},
+		{token.IDENT, "名字", 1, 5},
+		{token.ASSIGN, "=", 1, 8},
+		{token.STRING, "猴子", 1, 10},
+		{token.SEMICOLON, ";", 1, 14},
+		{token.LET, "let", 2, 1},
+		{token.IDENT, "café", 2, 5},
+		{token.ASSIGN, "=", 2, 10},
+		{token.IDENT, "名字", 2, 12},
+		{token.PLUS, "+", 2, 15},
+		{token.STRING, "!", 2, 17},
+		{token.SEMICOLON, ";", 2, 20},
+		{token.IDENT, "λ", 3, 1},
+		{token.EOF, "", 3, 2},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong, expected=%q, actual=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong, expected=%q, actual=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+		if tok.Pos.Line != tt.line || tok.Pos.Column != tt.column {
+			t.Fatalf("tests[%d] - position wrong, expected=%d:%d, actual=%d:%d",
+				i, tt.line, tt.column, tok.Pos.Line, tok.Pos.Column)
+		}
+	}
+}
+
+func TestNumberLiterals(t *testing.T) {
+	input := `0xFF 0o17 0b1010 1_000_000 3.14 1e9 2.5E-3 6e+2 1__0 0b12 1.x`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.INT, "0xFF"},
+		{token.INT, "0o17"},
+		{token.INT, "0b1010"},
+		{token.INT, "1_000_000"},
+		{token.FLOAT, "3.14"},
+		{token.FLOAT, "1e9"},
+		{token.FLOAT, "2.5E-3"},
+		{token.FLOAT, "6e+2"},
+		{token.INT, "1__0"}, //非法的分隔符留给解析器报告
+		{token.INT, "0b12"},
+		{token.INT, "1"}, //小数点之后不是数字时不属于数字字面量
+		{token.ILLEGAL, "."},
+		{token.IDENT, "x"},
+		{token.EOF, ""},
+	}
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong, expected=%q, actual=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong, expected=%q, actual=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestIllegalCharacters(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedLiteral string
+	}{
+		{"€", "€"},
+		{"\xff", "\uFFFD"},
+	}
+
+	for _, tt := range tests {
+		tok := New(tt.input).NextToken()
+		if tok.Type != token.ILLEGAL {
+			t.Errorf("input %q - tokentype wrong, expected=%q, actual=%q", tt.input, token.ILLEGAL, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Errorf("input %q - literal wrong, expected=%q, actual=%q", tt.input, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// 行注释
+let x = 1; // trailing
+/* block /* nested */ still comment */ x / 2;
+/**/ x`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.COMMENT, "// 行注释"},
+		{token.LET, "let"},
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.INT, "1"},
+		{token.SEMICOLON, ";"},
+		{token.COMMENT, "// trailing"},
+		{token.COMMENT, "/* block /* nested */ still comment */"},
+		{token.IDENT, "x"},
+		{token.SLASH, "/"},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.COMMENT, "/**/"},
+		{token.IDENT, "x"},
+		{token.EOF, ""},
+	}
+
+	l := New(input, WithComments())
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong, expected=%q, actual=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong, expected=%q, actual=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+		if tok.Type == token.COMMENT && tok.Literal != input[tok.Pos.Offset:tok.End.Offset] {
+			t.Fatalf("tests[%d] - comment span wrong, got=%q", i, input[tok.Pos.Offset:tok.End.Offset])
+		}
+	}
+
+	l = New(input)
+	for i, tt := range tests {
+		if tt.expectedType == token.COMMENT {
+			continue
+		}
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong without comments, expected=%q, actual=%q", i, tt.expectedType, tok.Type)
+		}
+	}
+	if len(l.Errors()) != 0 {
+		t.Fatalf("unexpected lexer errors: %v", l.Errors())
+	}
+}
+
+func TestUnterminatedBlockComment(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		hint     string
+	}{
+		{"let x = 1;\n  /* never closed", "2:3: unterminated block comment", ""},
+		{"/* outer /* inner */", "1:1: unterminated block comment", "block comments nest; 1 more */ needed"},
+		{"/* /* /*", "1:1: unterminated block comment", "block comments nest; 3 more */ needed"},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		}
+
+		errors := l.Errors()
+		if len(errors) != 1 {
+			t.Errorf("input %q - wrong number of errors. got=%d", tt.input, len(errors))
+			continue
+		}
+		if errors[0].String() != tt.expected {
+			t.Errorf("input %q - wrong error. expected=%q, got=%q", tt.input, tt.expected, errors[0].String())
+		}
+		if errors[0].Hint != tt.hint {
+			t.Errorf("input %q - wrong hint. expected=%q, got=%q", tt.input, tt.hint, errors[0].Hint)
+		}
+	}
+}
+
+func TestTrace(t *testing.T) {
+	var out bytes.Buffer
+	l := New("let x = 5;", WithTrace(&out))
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+	}
+
+	expected := "1:1\tLET\t\"let\"\n" +
+		"1:5\tIDENT\t\"x\"\n" +
+		"1:7\t=\t\"=\"\n" +
+		"1:9\tINT\t\"5\"\n" +
+		"1:10\t;\t\";\"\n" +
+		"1:11\tEOF\t\"\"\n"
+	if out.String() != expected {
+		t.Errorf("wrong trace. expected=%q, got=%q", expected, out.String())
+	}
+}
+
+func TestTwoCharOperatorsAtEndOfInput(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedType token.TokenType
+	}{
+		{"=", token.ASSIGN},
+		{"!", token.BANG},
+		{"let x =", token.ASSIGN},
+		{"x !", token.BANG},
+		{"x <", token.LT},
+		{"x &", token.BIT_AND},
+		{"x |", token.BIT_OR},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		var last token.Token
+		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+			last = tok
+		}
+		if last.Type != tt.expectedType {
+			t.Errorf("input %q - last tokentype wrong, expected=%q, actual=%q", tt.input, tt.expectedType, last.Type)
+		}
+	}
+}
+
+func FuzzNextToken(f *testing.F) {
+	seeds := []string{
+		"",
+		"=",
+		"!",
+		"let x =",
+		"let x = 5; x != 10 == true;",
+		`"unterminated`,
+		`"\u{1F600}\n"`,
+		"/* /* */",
+		"// comment",
+		"let 名字 = [1, 2][0];",
+		"\xff\xfe",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		l := New(input, WithComments())
+		last := token.Position{Line: 1, Column: 1}
+		for i := 0; ; i++ {
+			if i > len(input)+1 { //每个词法单元至少消耗一个字节
+				t.Fatalf("lexer did not reach EOF for %q", input)
+			}
+			tok := l.NextToken()
+			if tok.Pos.Offset < last.Offset || tok.End.Offset < tok.Pos.Offset || tok.End.Offset > len(input) {
+				t.Fatalf("token %q has invalid span %d-%d (previous end %d)", tok.Literal, tok.Pos.Offset, tok.End.Offset, last.Offset)
+			}
+			last = tok.End
+			if tok.Type == token.EOF {
+				return
+			}
+		}
+	})
+}
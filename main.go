@@ -1,13 +1,19 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"monkey/interp"
 	"monkey/repl"
 	"os"
 	"os/user"
 )
 
 func main() {
+	if len(os.Args) > 1 { //monkey script.mk：执行脚本文件而不是启动REPL
+		os.Exit(runFile(os.Args[1]))
+	}
+
 	user, err := user.Current()
 	if err != nil {
 		panic(err)
@@ -17,3 +23,23 @@ func main() {
 	repl.Start(os.Stdin, os.Stdout)
 
 }
+
+// runFile 执行filename中的脚本，出错时输出带有file:line:col位置的错误信息并返回非0的退出码
+func runFile(filename string) int {
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if _, err := interp.New().EvalFile(filename, string(src)); err != nil {
+		var parseErr *interp.ParseError
+		if errors.As(err, &parseErr) {
+			fmt.Fprint(os.Stderr, parseErr.Render())
+		} else {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		return 1
+	}
+	return 0
+}
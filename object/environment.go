@@ -0,0 +1,94 @@
+package object
+
+// OverflowMode 决定整数运算发生int64溢出时的处理方式
+type OverflowMode int
+
+const (
+	OverflowWrap    OverflowMode = iota //默认行为，与Go一样直接回绕
+	OverflowError                       //溢出时返回运行时错误
+	OverflowPromote                     //溢出时提升为任意精度整数BigInteger
+)
+
+// Environment 用于保存标识符与值之间的绑定关系，outer指向外层作用域
+type Environment struct {
+	store    map[string]Object
+	consts   map[string]bool //store中通过const绑定的名称
+	outer    *Environment
+	overflow OverflowMode //只在最外层的环境中有效
+}
+
+func NewEnvironment() *Environment {
+	s := make(map[string]Object)
+	return &Environment{store: s, consts: make(map[string]bool), outer: nil}
+}
+
+// NewEnclosedEnvironment 创建一个嵌套在outer中的新作用域
+func NewEnclosedEnvironment(outer *Environment) *Environment {
+	env := NewEnvironment()
+	env.outer = outer
+	return env
+}
+
+func (e *Environment) Get(name string) (Object, bool) {
+	obj, ok := e.store[name]
+	if !ok && e.outer != nil { //当前作用域中找不到时，沿着作用域链向外查找
+		obj, ok = e.outer.Get(name)
+	}
+	return obj, ok
+}
+
+func (e *Environment) Set(name string, val Object) Object {
+	e.store[name] = val
+	delete(e.consts, name)
+	return val
+}
+
+// SetConst 在当前作用域中创建一个常量绑定
+func (e *Environment) SetConst(name string, val Object) Object {
+	e.store[name] = val
+	e.consts[name] = true
+	return val
+}
+
+// IsConst 判断沿着作用域链找到的最近一个名为name的绑定是否是常量
+func (e *Environment) IsConst(name string) bool {
+	for env := e; env != nil; env = env.outer {
+		if _, ok := env.store[name]; ok {
+			return env.consts[name]
+		}
+	}
+	return false
+}
+
+// IsLocalConst 判断当前作用域中是否已经有名为name的常量，常量不能在同一个作用域中被重新声明
+func (e *Environment) IsLocalConst(name string) bool {
+	return e.consts[name]
+}
+
+// Assign 沿着作用域链查找最近的一个名为name的绑定并更新它的值，找不到时返回false
+func (e *Environment) Assign(name string, val Object) bool {
+	for env := e; env != nil; env = env.outer {
+		if _, ok := env.store[name]; ok {
+			env.store[name] = val
+			return true
+		}
+	}
+	return false
+}
+
+// OverflowMode 返回整数运算的溢出处理方式。它属于整个求值过程而不是某个作用域，因此总是从最外层的环境中读取
+func (e *Environment) OverflowMode() OverflowMode {
+	return e.root().overflow
+}
+
+// SetOverflowMode 设置整数运算的溢出处理方式，对共享同一个最外层环境的所有作用域生效
+func (e *Environment) SetOverflowMode(mode OverflowMode) {
+	e.root().overflow = mode
+}
+
+func (e *Environment) root() *Environment {
+	for e.outer != nil {
+		e = e.outer
+	}
+	return e
+}
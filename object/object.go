@@ -1,13 +1,36 @@
 package object
 
-import "fmt"
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"math/big"
+	"monkey/ast"
+	"monkey/token"
+	"sort"
+	"strconv"
+	"strings"
+)
 
 type ObjectType string
 
 const (
 	INTEGER_OBJ = "INTEGER"
+	BIG_INT_OBJ = "BIG_INTEGER"
+	FLOAT_OBJ   = "FLOAT"
 	BOOLEAN_OBJ = "BOOLEAN"
+	STRING_OBJ  = "STRING"
 	NULL_OBJ    = "NULL"
+	ERROR_OBJ   = "ERROR"
+
+	RETURN_VALUE_OBJ = "RETURN_VALUE"
+	BREAK_OBJ        = "BREAK"
+	CONTINUE_OBJ     = "CONTINUE"
+
+	FUNCTION_OBJ = "FUNCTION"
+	BUILTIN_OBJ  = "BUILTIN"
+	ARRAY_OBJ    = "ARRAY"
+	HASH_OBJ     = "HASH"
 )
 
 type Object interface {
@@ -15,6 +38,17 @@ type Object interface {
 	Inspect() string
 }
 
+// HashKey 是对象在哈希表中的键，Type用于区分值相同但类型不同的对象（例如1和true）
+type HashKey struct {
+	Type  ObjectType
+	Value uint64
+}
+
+// Hashable 由可以作为哈希表键的对象实现
+type Hashable interface {
+	HashKey() HashKey
+}
+
 type Integer struct {
 	Value int64
 }
@@ -25,6 +59,36 @@ func (i *Integer) Inspect() string {
 func (i *Integer) Type() ObjectType {
 	return INTEGER_OBJ
 }
+func (i *Integer) HashKey() HashKey {
+	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
+}
+
+type BigInteger struct { //任意精度整数，在整数运算溢出并开启提升模式时使用
+	Value *big.Int
+}
+
+func (bi *BigInteger) Inspect() string {
+	return bi.Value.String()
+}
+func (bi *BigInteger) Type() ObjectType {
+	return BIG_INT_OBJ
+}
+
+type Float struct {
+	Value float64
+}
+
+// Inspect 总是保留小数点或者指数部分，使1.0不会被显示成与整数相同的1
+func (f *Float) Inspect() string {
+	s := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eIN") { //Inf和NaN同样不需要补充小数部分
+		s += ".0"
+	}
+	return s
+}
+func (f *Float) Type() ObjectType {
+	return FLOAT_OBJ
+}
 
 type Boolean struct {
 	Value bool
@@ -36,6 +100,31 @@ func (b *Boolean) Inspect() string {
 func (b *Boolean) Type() ObjectType {
 	return BOOLEAN_OBJ
 }
+func (b *Boolean) HashKey() HashKey {
+	var value uint64
+	if b.Value {
+		value = 1
+	} else {
+		value = 0
+	}
+	return HashKey{Type: b.Type(), Value: value}
+}
+
+type String struct {
+	Value string
+}
+
+func (s *String) Inspect() string {
+	return s.Value
+}
+func (s *String) Type() ObjectType {
+	return STRING_OBJ
+}
+func (s *String) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write([]byte(s.Value))
+	return HashKey{Type: s.Type(), Value: h.Sum64()}
+}
 
 type Null struct {
 }
@@ -46,3 +135,207 @@ func (n *Null) Inspect() string {
 func (n *Null) Type() ObjectType {
 	return NULL_OBJ
 }
+
+type ReturnValue struct { //对返回值进行包装，用于在求值过程中标记需要提前退出
+	Value Object
+}
+
+func (rv *ReturnValue) Inspect() string {
+	return rv.Value.Inspect()
+}
+func (rv *ReturnValue) Type() ObjectType {
+	return RETURN_VALUE_OBJ
+}
+
+type Break struct{} //与ReturnValue类似，用于在求值过程中标记需要跳出当前循环
+
+func (b *Break) Inspect() string {
+	return "break"
+}
+func (b *Break) Type() ObjectType {
+	return BREAK_OBJ
+}
+
+type Continue struct{} //用于在求值过程中标记需要结束本次迭代
+
+func (c *Continue) Inspect() string {
+	return "continue"
+}
+func (c *Continue) Type() ObjectType {
+	return CONTINUE_OBJ
+}
+
+type Error struct { //运行时错误，携带错误信息
+	Message string
+	Pos     token.Position //出错的节点在源代码中的位置
+}
+
+func (e *Error) Inspect() string {
+	if e.Pos.IsValid() {
+		return "ERROR: " + e.Pos.String() + ": " + e.Message
+	}
+	return "ERROR: " + e.Message
+}
+func (e *Error) Type() ObjectType {
+	return ERROR_OBJ
+}
+
+type Function struct { //函数对象，Env保存了函数定义时所在的环境，用于实现闭包
+	Parameters []*ast.Identifier
+	Body       *ast.BlockStatement
+	Env        *Environment
+}
+
+func (f *Function) Type() ObjectType {
+	return FUNCTION_OBJ
+}
+func (f *Function) Inspect() string {
+	var out bytes.Buffer
+
+	params := []string{}
+	for _, p := range f.Parameters {
+		params = append(params, p.String())
+	}
+
+	out.WriteString("fn")
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") {\n")
+	out.WriteString(f.Body.String())
+	out.WriteString("\n}")
+
+	return out.String()
+}
+
+// BuiltinFunction 是用Go实现的内置函数的签名
+type BuiltinFunction func(args ...Object) Object
+
+type Builtin struct {
+	Fn BuiltinFunction
+}
+
+func (b *Builtin) Type() ObjectType {
+	return BUILTIN_OBJ
+}
+func (b *Builtin) Inspect() string {
+	return "builtin function"
+}
+
+type Array struct {
+	Elements []Object
+}
+
+func (a *Array) Type() ObjectType {
+	return ARRAY_OBJ
+}
+func (a *Array) Inspect() string {
+	return a.inspect(map[Object]bool{})
+}
+
+// inspect 记录当前正在输出的容器，遇到引用自身的数组或哈希表时输出[...]或{...}，避免无限递归
+func (a *Array) inspect(visiting map[Object]bool) string {
+	if visiting[a] {
+		return "[...]"
+	}
+	visiting[a] = true
+	defer delete(visiting, a)
+
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, e := range a.Elements {
+		elements = append(elements, inspectElement(e, visiting))
+	}
+
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+
+	return out.String()
+}
+
+// orderedKeys 返回按照插入顺序排列的键。没有通过Set加入的键（例如直接填充Pairs得到的哈希表）
+// 按照键的输出排在最后，保证输出的顺序是确定的
+func (h *Hash) orderedKeys() []HashKey {
+	if len(h.Keys) == len(h.Pairs) {
+		return h.Keys
+	}
+	keys := make([]HashKey, 0, len(h.Pairs))
+	seen := make(map[HashKey]bool, len(h.Keys))
+	for _, key := range h.Keys {
+		if _, ok := h.Pairs[key]; ok && !seen[key] {
+			keys = append(keys, key)
+			seen[key] = true
+		}
+	}
+	var rest []HashKey
+	for key := range h.Pairs {
+		if !seen[key] {
+			rest = append(rest, key)
+		}
+	}
+	sort.Slice(rest, func(i, j int) bool { return h.Pairs[rest[i]].Key.Inspect() < h.Pairs[rest[j]].Key.Inspect() })
+	return append(keys, rest...)
+}
+
+// inspectElement 输出容器中的元素，元素本身是容器时沿用同一份visiting记录
+func inspectElement(obj Object, visiting map[Object]bool) string {
+	switch obj := obj.(type) {
+	case *Array:
+		return obj.inspect(visiting)
+	case *Hash:
+		return obj.inspect(visiting)
+	}
+	return obj.Inspect()
+}
+
+type HashPair struct { //同时保存原始的键对象，便于Inspect输出
+	Key   Object
+	Value Object
+}
+
+type Hash struct {
+	Pairs map[HashKey]HashPair
+	Keys  []HashKey //键第一次加入的顺序，Inspect按照这个顺序输出
+}
+
+func NewHash() *Hash {
+	return &Hash{Pairs: make(map[HashKey]HashPair)}
+}
+
+// Set 添加或者更新一个键值对，新的键排在已有的键之后，更新已有的键不改变它的位置
+func (h *Hash) Set(key HashKey, pair HashPair) {
+	if _, ok := h.Pairs[key]; !ok {
+		h.Keys = append(h.Keys, key)
+	}
+	h.Pairs[key] = pair
+}
+
+func (h *Hash) Type() ObjectType {
+	return HASH_OBJ
+}
+func (h *Hash) Inspect() string {
+	return h.inspect(map[Object]bool{})
+}
+
+func (h *Hash) inspect(visiting map[Object]bool) string {
+	if visiting[h] {
+		return "{...}"
+	}
+	visiting[h] = true
+	defer delete(visiting, h)
+
+	var out bytes.Buffer
+
+	pairs := []string{}
+	for _, key := range h.orderedKeys() {
+		pair := h.Pairs[key]
+		pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(), inspectElement(pair.Value, visiting)))
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
@@ -0,0 +1,119 @@
+package object
+
+import "testing"
+
+func TestStringHashKey(t *testing.T) {
+	hello1 := &String{Value: "Hello World"}
+	hello2 := &String{Value: "Hello World"}
+	diff1 := &String{Value: "My name is johnny"}
+	diff2 := &String{Value: "My name is johnny"}
+
+	if hello1.HashKey() != hello2.HashKey() {
+		t.Errorf("strings with same content have different hash keys")
+	}
+
+	if diff1.HashKey() != diff2.HashKey() {
+		t.Errorf("strings with same content have different hash keys")
+	}
+
+	if hello1.HashKey() == diff1.HashKey() {
+		t.Errorf("strings with different content have same hash keys")
+	}
+}
+
+func TestHashKeyDistinguishesTypes(t *testing.T) {
+	one := &Integer{Value: 1}
+	yes := &Boolean{Value: true}
+
+	if one.HashKey() == yes.HashKey() {
+		t.Errorf("integer 1 and true have same hash keys")
+	}
+}
+
+func TestFloatInspect(t *testing.T) {
+	tests := []struct {
+		value    float64
+		expected string
+	}{
+		{1, "1.0"},
+		{-2, "-2.0"},
+		{3.25, "3.25"},
+		{1e21, "1e+21"},
+		{0.000001, "1e-06"},
+	}
+
+	for _, tt := range tests {
+		f := &Float{Value: tt.value}
+		if f.Inspect() != tt.expected {
+			t.Errorf("Inspect() wrong. expected=%q, got=%q", tt.expected, f.Inspect())
+		}
+	}
+}
+
+func TestCyclicInspect(t *testing.T) {
+	one := &Integer{Value: 1}
+	arr := &Array{Elements: []Object{one}}
+	arr.Elements = append(arr.Elements, arr)
+	if arr.Inspect() != "[1, [...]]" {
+		t.Errorf("Inspect() wrong. got=%q", arr.Inspect())
+	}
+
+	key := &String{Value: "self"}
+	hash := &Hash{Pairs: map[HashKey]HashPair{}}
+	hash.Pairs[key.HashKey()] = HashPair{Key: key, Value: hash}
+	if hash.Inspect() != "{self: {...}}" {
+		t.Errorf("Inspect() wrong. got=%q", hash.Inspect())
+	}
+
+	//同一个数组出现多次但没有形成环时应完整输出
+	shared := &Array{Elements: []Object{one}}
+	outer := &Array{Elements: []Object{shared, shared}}
+	if outer.Inspect() != "[[1], [1]]" {
+		t.Errorf("Inspect() wrong. got=%q", outer.Inspect())
+	}
+}
+
+func TestHashInspectOrder(t *testing.T) {
+	hash := NewHash()
+	for _, k := range []string{"z", "a", "m"} {
+		key := &String{Value: k}
+		hash.Set(key.HashKey(), HashPair{Key: key, Value: &Integer{Value: 1}})
+	}
+	a := &String{Value: "a"}
+	hash.Set(a.HashKey(), HashPair{Key: a, Value: &Integer{Value: 2}}) //更新已有的键不改变顺序
+
+	for i := 0; i < 10; i++ {
+		if hash.Inspect() != "{z: 1, a: 2, m: 1}" {
+			t.Fatalf("Inspect() wrong. got=%q", hash.Inspect())
+		}
+	}
+
+	//直接填充Pairs得到的哈希表按照键排序输出
+	raw := &Hash{Pairs: map[HashKey]HashPair{}}
+	for _, k := range []string{"c", "b", "a"} {
+		key := &String{Value: k}
+		raw.Pairs[key.HashKey()] = HashPair{Key: key, Value: &Integer{Value: 0}}
+	}
+	if raw.Inspect() != "{a: 0, b: 0, c: 0}" {
+		t.Errorf("Inspect() wrong. got=%q", raw.Inspect())
+	}
+}
+
+func TestEnvironmentAssign(t *testing.T) {
+	outer := NewEnvironment()
+	outer.Set("x", &Integer{Value: 1})
+	inner := NewEnclosedEnvironment(outer)
+
+	if !inner.Assign("x", &Integer{Value: 2}) {
+		t.Fatalf("Assign did not find x in the outer environment")
+	}
+	if val, _ := outer.Get("x"); val.(*Integer).Value != 2 {
+		t.Errorf("outer x was not updated. got=%s", val.Inspect())
+	}
+	if _, ok := inner.store["x"]; ok {
+		t.Errorf("Assign created a new binding in the inner environment")
+	}
+	if inner.Assign("y", &Integer{Value: 3}) {
+		t.Errorf("Assign succeeded for an undeclared name")
+	}
+}
@@ -2,37 +2,66 @@ package parser
 
 import (
 	"fmt"
+	"io"
 	"monkey/ast"
+	"monkey/diagnostic"
 	"monkey/lexer"
 	"monkey/token"
 	"strconv"
+	"strings"
 )
 
 const (
 	_ int = iota
 	LOWEST
+	ASSIGN      //= += -= *= /=，右结合
+	TERNARY     //? :，右结合
+	LOGICAL_OR  //|| 逻辑运算符优先级最低，位运算符介于逻辑运算符与比较运算符之间，与C语言保持一致
+	LOGICAL_AND //&&
+	BIT_OR      //|
+	BIT_XOR     //^
+	BIT_AND     //&
 	EQUALS      //==
 	LESSGREATER //< or >
+	SHIFT       //<< or >>
 	SUM         //+
 	PRODUCT     //*
 	PREFIX      //--,++,-,!...
 	CALL        //add(x+y)
+	INDEX       //array[index]
 )
 
-//添加优先级表
+// 添加优先级表
 var precedences = map[token.TokenType]int{
-	token.EQ:       EQUALS,
-	token.NOT_EQ:   EQUALS,
-	token.LT:       LESSGREATER,
-	token.GT:       LESSGREATER,
-	token.PLUS:     SUM,
-	token.MINUS:    SUM,
-	token.SLASH:    PRODUCT,
-	token.ASTERISK: PRODUCT,
-	token.LPAREN:   CALL,
-}
-
-//定义前缀函数和中缀函数，并设置这两种之间的关联（通过参数传递）
+	token.ASSIGN:          ASSIGN,
+	token.PLUS_ASSIGN:     ASSIGN,
+	token.MINUS_ASSIGN:    ASSIGN,
+	token.ASTERISK_ASSIGN: ASSIGN,
+	token.SLASH_ASSIGN:    ASSIGN,
+	token.QUESTION:        TERNARY,
+	token.OR:              LOGICAL_OR,
+	token.AND:             LOGICAL_AND,
+	token.BIT_OR:          BIT_OR,
+	token.BIT_XOR:         BIT_XOR,
+	token.BIT_AND:         BIT_AND,
+	token.EQ:              EQUALS,
+	token.NOT_EQ:          EQUALS,
+	token.LT:              LESSGREATER,
+	token.GT:              LESSGREATER,
+	token.LT_EQ:           LESSGREATER,
+	token.GT_EQ:           LESSGREATER,
+	token.SHL:             SHIFT,
+	token.SHR:             SHIFT,
+	token.PLUS:            SUM,
+	token.MINUS:           SUM,
+	token.SLASH:           PRODUCT,
+	token.ASTERISK:        PRODUCT,
+	token.PERCENT:         PRODUCT,
+	token.LPAREN:          CALL,
+	token.LBRACKET:        INDEX,
+}
+
+// 定义前缀函数和中缀函数，并设置这两种之间的关联（通过参数传递）
 type (
 	prefixParseFn func() ast.Expression
 	infixParseFn  func(expression ast.Expression) ast.Expression
@@ -41,18 +70,30 @@ type (
 type Parser struct {
 	l *lexer.Lexer
 
-	curToken  token.Token
-	peerToken token.Token
-	errors    []string
+	curToken   token.Token
+	peerToken  token.Token
+	errors     []diagnostic.Diagnostic
+	panicMode  bool //遇到错误之后进入恐慌模式，直到在语句边界重新同步之前，不再记录新的错误
+	lexErrors  int  //已经合并到errors中的词法错误数量
+	loopDepth  int  //当前所在的循环嵌套层数，用于检查break和continue是否出现在循环之外
+	inArmBlock bool //正在解析match分支中=>之后的代码块，用于提示哈希表字面量需要加括号
+	scopes     []scope
+
+	traceOut   io.Writer //为nil时不输出跟踪信息
+	traceLevel int
 
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
 }
 
-func New(l *lexer.Lexer) *Parser {
+func New(l *lexer.Lexer, opts ...Option) *Parser {
 	p := &Parser{
 		l:      l,
-		errors: []string{},
+		errors: []diagnostic.Diagnostic{},
+		scopes: []scope{{}},
+	}
+	for _, opt := range opts {
+		opt(p)
 	}
 	//读取两个token
 	p.nextToken()
@@ -62,13 +103,22 @@ func New(l *lexer.Lexer) *Parser {
 	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
 	p.registerPrefix(token.INT, p.parseIntegerLiteral)
+	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
+	p.registerPrefix(token.STRING, p.parseStringLiteral)
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
+	p.registerPrefix(token.TILDE, p.parsePrefixExpression)
 	p.registerPrefix(token.TRUE, p.parseBoolean)
 	p.registerPrefix(token.FALSE, p.parseBoolean)
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
 	p.registerPrefix(token.IF, p.parseIfExpression)
+	p.registerPrefix(token.WHILE, p.parseWhileExpression)
+	p.registerPrefix(token.FOR, p.parseForInExpression)
+	p.registerPrefix(token.MATCH, p.parseMatchExpression)
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
+	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
+	//代码块只会出现在if和fn之后，并且由parseBlockStatement直接解析，因此出现在表达式位置上的左大括号一定是哈希字面量
+	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
 	//关联infix函数
 	p.infixParseFns = make(map[token.TokenType]infixParseFn)
 	p.registerInfix(token.PLUS, p.parseInfixExpression)
@@ -79,9 +129,26 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.EQ, p.parseInfixExpression)
 	p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.LT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.GT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.PERCENT, p.parseInfixExpression)
+	p.registerInfix(token.AND, p.parseInfixExpression)
+	p.registerInfix(token.OR, p.parseInfixExpression)
+	p.registerInfix(token.BIT_AND, p.parseInfixExpression)
+	p.registerInfix(token.BIT_OR, p.parseInfixExpression)
+	p.registerInfix(token.BIT_XOR, p.parseInfixExpression)
+	p.registerInfix(token.SHL, p.parseInfixExpression)
+	p.registerInfix(token.SHR, p.parseInfixExpression)
 
 	//这里需要为调用表达式的左括号设置一个中缀调用的函数，因为在解析调用函数的时候，词法分析器只能够识别标识符，无法确定这个标识符代表的究竟是变量还是函数。
+	p.registerInfix(token.ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.PLUS_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.MINUS_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.ASTERISK_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.SLASH_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.QUESTION, p.parseTernaryExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
+	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
 	return p
 }
 
@@ -93,23 +160,68 @@ func (p *Parser) registerInfix(tokenType token.TokenType, fn infixParseFn) {
 	p.infixParseFns[tokenType] = fn
 }
 
+// tokenError 记录一条覆盖词法单元tok的错误
+func (p *Parser) tokenError(tok token.Token, format string, a ...interface{}) *diagnostic.Diagnostic {
+	return p.spanError(tok.Pos, tok.End, format, a...)
+}
+
+// spanError 与tokenError相同，用于标记跨越多个词法单元的源代码范围，例如一个完整的表达式
+func (p *Parser) spanError(start, end token.Position, format string, a ...interface{}) *diagnostic.Diagnostic {
+	d := diagnostic.Errorf(start, end, format, a...)
+	if p.panicMode { //同一条语句中由第一个错误引起的级联错误直接丢弃
+		return &d
+	}
+	p.panicMode = true
+	p.errors = append(p.errors, d)
+	return &p.errors[len(p.errors)-1]
+}
+
+// keywordAsNameError 报告把关键字用作绑定名称的错误，比笼统的expected IDENT更容易理解
+func (p *Parser) keywordAsNameError(tok token.Token) {
+	d := p.tokenError(tok, "cannot use keyword %q as a name", tok.Literal)
+	d.Hint = fmt.Sprintf("%q is reserved; choose a different name", tok.Literal)
+}
+
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
-	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+	if t == token.ILLEGAL && p.reportedByLexer(p.curToken) { //词法分析器已经给出了更具体的错误，只进入恐慌模式
+		p.panicMode = true
+		return
+	}
+	d := p.tokenError(p.curToken, "no prefix parse function for %s found", t)
+	if t == token.COLON && p.inArmBlock { //例如_ => {"k": 1}，{被当作了代码块的开始
+		d.Hint = `a { after => starts a block; wrap hash literals in parentheses: _ => ({"k": 1})`
+	}
+}
+
+// reportedByLexer 判断词法分析器是否已经在tok的范围内报告过错误，例如字符串中非法的转义序列
+func (p *Parser) reportedByLexer(tok token.Token) bool {
+	for _, d := range p.l.Errors() {
+		if d.Span.Start.Offset >= tok.Pos.Offset && d.Span.Start.Offset < tok.End.Offset {
+			return true
+		}
+	}
+	return false
 }
 
 func (p *Parser) nextToken() {
 	p.curToken = p.peerToken
 	p.peerToken = p.l.NextToken()
+	for p.peerToken.Type == token.COMMENT { //语法分析不关心注释
+		p.peerToken = p.l.NextToken()
+	}
+	if errs := p.l.Errors(); len(errs) > p.lexErrors { //词法错误与语法错误一起按照出现的顺序报告
+		p.errors = append(p.errors, errs[p.lexErrors:]...)
+		p.lexErrors = len(errs)
+	}
 }
 
-func (p *Parser) Errors() []string {
+// Errors 返回语法分析过程中产生的诊断信息，可以使用diagnostic.Render输出带有源代码片段的错误
+func (p *Parser) Errors() []diagnostic.Diagnostic {
 	return p.errors
 }
 
-func (p *Parser) peekError(t token.TokenType) {
-	msg := fmt.Sprintf("expected next token to be %s, got %s instead", t, p.peerToken.Type)
-	p.errors = append(p.errors, msg)
+func (p *Parser) peekError(t token.TokenType) *diagnostic.Diagnostic {
+	return p.tokenError(p.peerToken, "expected next token to be %s, got %s instead", t, p.peerToken.Type)
 }
 
 func (p *Parser) peekPrecedence() int {
@@ -130,7 +242,12 @@ func (p *Parser) ParseProgram() *ast.Program {
 	program := &ast.Program{}              //首先构建一个空的Program对象
 	program.Statements = []ast.Statement{} //构建Program对象的Statement成员变量
 	for p.curToken.Type != token.EOF {
+		start := p.curToken
 		stmt := p.parseStatement()
+		if p.panicMode { //丢弃出错的语句，从下一条语句开始继续分析
+			p.synchronize(start, false)
+			continue
+		}
 		if stmt != nil {
 			program.Statements = append(program.Statements, stmt)
 		}
@@ -139,12 +256,48 @@ func (p *Parser) ParseProgram() *ast.Program {
 	return program
 }
 
+// synchronize 在出错之后跳过词法单元，直到下一条语句的开头，并退出恐慌模式。
+// 语句边界包括分号之后、let和return关键字，以及代码块的右大括号（inBlock为true时保留给parseBlockStatement处理）。
+// 跳过的过程中会对大括号进行配对，避免在出错语句内部的代码块中停下来。start为出错语句的第一个词法单元
+func (p *Parser) synchronize(start token.Token, inBlock bool) {
+	p.panicMode = false
+	depth := 0
+	for !p.curTokenIs(token.EOF) {
+		switch p.curToken.Type {
+		case token.SEMICOLON:
+			if depth == 0 {
+				p.nextToken()
+				return
+			}
+		case token.LET, token.CONST, token.RETURN:
+			if depth == 0 && p.curToken.Pos != start.Pos {
+				return
+			}
+		case token.LBRACE:
+			depth++
+		case token.RBRACE:
+			if depth == 0 && inBlock {
+				return
+			}
+			if depth > 0 {
+				depth--
+			}
+		}
+		p.nextToken()
+	}
+}
+
 func (p *Parser) parseStatement() ast.Statement {
+	defer p.untrace(p.trace("parseStatement"))
 	switch p.curToken.Type {
 	case token.LET:
 		return p.parseLetStatement()
+	case token.CONST:
+		return p.parseConstStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.BREAK, token.CONTINUE:
+		return p.parseLoopControlStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
@@ -152,10 +305,18 @@ func (p *Parser) parseStatement() ast.Statement {
 
 func (p *Parser) parseLetStatement() *ast.LetStatement { //关于这里为什么要加指针返回值，是因为LetStatement接口实现时使用的是指针接收者，这个指针接收者继承了Statement的方法集。但是其值接收者并没有（因为指针接收者和值接收者二者的方法集是不同的）
 	//当这里用了值接收者作为返回对象时，由于值接收者并没有实现Statement接口的所有方法，因此在作为泛型时它就不能作为Statement的返回对象
+	defer p.untrace(p.trace("parseLetStatement"))
 	stmt := &ast.LetStatement{Token: p.curToken}
+	if token.IsKeyword(p.peerToken) {
+		p.keywordAsNameError(p.peerToken)
+		return nil
+	}
 	if !p.expectPeek(token.IDENT) {
 		return nil
 	}
+	if !p.checkRedeclaration(p.curToken) {
+		return nil
+	}
 	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 
 	if !p.expectPeek(token.ASSIGN) {
@@ -168,10 +329,40 @@ func (p *Parser) parseLetStatement() *ast.LetStatement { //关于这里为什么
 	if p.peerTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
+	p.declare(stmt.Name, false)
+	return stmt
+}
+
+func (p *Parser) parseConstStatement() *ast.ConstStatement {
+	defer p.untrace(p.trace("parseConstStatement"))
+	stmt := &ast.ConstStatement{Token: p.curToken}
+	if token.IsKeyword(p.peerToken) {
+		p.keywordAsNameError(p.peerToken)
+		return nil
+	}
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	if !p.checkRedeclaration(p.curToken) {
+		return nil
+	}
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.ASSIGN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Value = p.parseExpression(LOWEST)
+	if p.peerTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	p.declare(stmt.Name, true)
 	return stmt
 }
 
 func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
+	defer p.untrace(p.trace("parseReturnStatement"))
 	stmt := &ast.ReturnStatement{Token: p.curToken}
 	p.nextToken()
 	//@todo: 跳过对于表达式的解析
@@ -199,7 +390,18 @@ func (p *Parser) expectPeek(t token.TokenType) bool {
 	}
 }
 
+// expectClosing 与expectPeek相同，用于期望一个与open配对的右括号，出错时提示与之配对的左括号的位置
+func (p *Parser) expectClosing(t token.TokenType, open token.Token) bool {
+	if p.peerTokenIs(t) {
+		p.nextToken()
+		return true
+	}
+	p.peekError(t).Hint = fmt.Sprintf("to match %s at %s", open.Literal, open.Pos)
+	return false
+}
+
 func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
+	defer p.untrace(p.trace("parseExpressionStatement"))
 	stmt := &ast.ExpressionStatement{Token: p.curToken}
 	stmt.Expression = p.parseExpression(LOWEST)
 
@@ -211,6 +413,7 @@ func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 }
 
 func (p *Parser) parseExpression(precedence int) ast.Expression {
+	defer p.untrace(p.trace("parseExpression"))
 	prefix := p.prefixParseFns[p.curToken.Type]
 	if prefix == nil {
 		p.noPrefixParseFnError(p.curToken.Type)
@@ -234,22 +437,58 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 }
 
 func (p *Parser) parseIdentifier() ast.Expression {
+	defer p.untrace(p.trace("parseIdentifier"))
 	return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 }
 
 func (p *Parser) parseIntegerLiteral() ast.Expression {
+	defer p.untrace(p.trace("parseIntegerLiteral"))
 	lit := &ast.IntegerLiteral{Token: p.curToken}
+	if literal := p.curToken.Literal; len(literal) > 1 && literal[0] == '0' && (isDigit(literal[1]) || literal[1] == '_') {
+		//strconv会把010当作旧式的八进制数，八进制数必须使用0o前缀，因此直接拒绝这种容易误解的写法
+		d := p.tokenError(p.curToken, "leading zeros are not allowed in integer literal %q", literal)
+		d.Hint = fmt.Sprintf("write %s for a decimal number, or use the 0o prefix for an octal number", decimalWithoutLeadingZeros(literal))
+		return nil
+	}
 	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.tokenError(p.curToken, "could not parse %q as integer", p.curToken.Literal)
+		return nil
+	}
+	lit.Value = value
+	return lit
+}
+
+// decimalWithoutLeadingZeros 去掉十进制整数字面量开头的0和下划线，用于给出修改建议
+func decimalWithoutLeadingZeros(literal string) string {
+	if trimmed := strings.TrimLeft(literal, "0_"); trimmed != "" {
+		return trimmed
+	}
+	return "0"
+}
+
+func isDigit(ch byte) bool {
+	return '0' <= ch && ch <= '9'
+}
+
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	defer p.untrace(p.trace("parseFloatLiteral"))
+	lit := &ast.FloatLiteral{Token: p.curToken}
+	value, err := strconv.ParseFloat(p.curToken.Literal, 64)
+	if err != nil { //包括超出float64范围的情况
+		p.tokenError(p.curToken, "could not parse %q as float", p.curToken.Literal)
 		return nil
 	}
 	lit.Value = value
 	return lit
 }
 
+func (p *Parser) parseStringLiteral() ast.Expression {
+	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+}
+
 func (p *Parser) parsePrefixExpression() ast.Expression {
+	defer p.untrace(p.trace("parsePrefixExpression"))
 	expression := &ast.PrefixExpression{Token: p.curToken, Operator: p.curToken.Literal}
 	p.nextToken()
 	expression.Right = p.parseExpression(PREFIX)
@@ -257,6 +496,7 @@ func (p *Parser) parsePrefixExpression() ast.Expression {
 }
 
 func (p *Parser) parseInfixExpression(leftExp ast.Expression) ast.Expression {
+	defer p.untrace(p.trace("parseInfixExpression"))
 	expression := &ast.InfixExpression{Token: p.curToken, Left: leftExp, Operator: p.curToken.Literal}
 	precedence := p.curPrecedence()
 	p.nextToken()                                    //由于已经完成了左值的读取，那么就需要继续获取下一个词法单元
@@ -264,30 +504,57 @@ func (p *Parser) parseInfixExpression(leftExp ast.Expression) ast.Expression {
 	return expression
 }
 
+// parseAssignExpression 解析赋值表达式，只有标识符和下标表达式可以作为赋值的目标。
+// 右值使用比ASSIGN低一级的优先级解析，使a = b = 1被解析为a = (b = 1)
+func (p *Parser) parseAssignExpression(target ast.Expression) ast.Expression {
+	defer p.untrace(p.trace("parseAssignExpression"))
+	expression := &ast.AssignExpression{Token: p.curToken, Target: target, Operator: p.curToken.Literal}
+	if target == nil || p.panicMode { //左侧解析失败时错误已经记录过了，并且左侧可能包含nil的子节点
+		return nil
+	}
+	switch target := target.(type) {
+	case *ast.Identifier:
+		if !p.checkConstAssignment(target) {
+			return nil
+		}
+	case *ast.IndexExpression: //常量只保证绑定不变，数组和哈希表中的元素仍然可以修改
+	default:
+		d := p.spanError(target.Pos(), target.End(), "invalid assignment target: %s", target.String())
+		d.Hint = "only variables and index expressions can be assigned to"
+		return nil
+	}
+	p.nextToken()
+	expression.Value = p.parseExpression(ASSIGN - 1)
+	return expression
+}
+
 func (p *Parser) parseBoolean() ast.Expression {
 	return &ast.Boolean{Token: p.curToken, Value: p.curTokenIs(token.TRUE)}
 }
 
 func (p *Parser) parseGroupedExpression() ast.Expression {
+	lparen := p.curToken
 	p.nextToken()
 
 	exp := p.parseExpression(LOWEST) //由于parseExpression这个函数内部也有递归函数，同时所有的递归函数中，传递优先级的参数只会增加不会减少，能够控制
 	//这个优先级参数参数的方式就是在外部调用的时候传递一个优先级参数。当遇到左括号的时候，就强制调用一个新的parseExpression递归函数，由于递归程序的执行顺序是永远先于for循环的，因此这里能够顺利地得到结果。
 
-	if !p.expectPeek(token.RPAREN) { //如果没有遇到右括号，则说明出现了语法错误
+	if !p.expectClosing(token.RPAREN, lparen) { //如果没有遇到右括号，则说明出现了语法错误
 		return nil //返回一个空的值
 	}
 	return exp
 }
 
 func (p *Parser) parseIfExpression() ast.Expression {
+	defer p.untrace(p.trace("parseIfExpression"))
 	expression := &ast.IfExpression{Token: p.curToken}
 	if !p.expectPeek(token.LPAREN) { //如果不是左括号，则说明语法分析出现了错误，暂时返回nil
 		return nil
 	}
+	lparen := p.curToken
 	p.nextToken()                                    //将词法指针移动到表达式的开头
 	expression.Condition = p.parseExpression(LOWEST) //去解析IF语句里面的表达式
-	if !p.expectPeek(token.RPAREN) {                 //解析完条件表达式之后，如果遇到的不是右括号，则说明语法分析器出现了问题，返回nil
+	if !p.expectClosing(token.RPAREN, lparen) {      //解析完条件表达式之后，如果遇到的不是右括号，则说明语法分析器出现了问题，返回nil
 		return nil
 	}
 
@@ -300,6 +567,20 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	if p.peerTokenIs(token.ELSE) {
 		p.nextToken()
 
+		if p.peerTokenIs(token.IF) { //else if被转换为只包含一个if表达式的else代码块
+			p.nextToken()
+			ifToken := p.curToken
+			nested := p.parseIfExpression()
+			if nested == nil {
+				return nil
+			}
+			expression.Alternative = &ast.BlockStatement{
+				Token:      ifToken,
+				Statements: []ast.Statement{&ast.ExpressionStatement{Token: ifToken, Expression: nested}},
+			}
+			return expression
+		}
+
 		if !p.expectPeek(token.LBRACE) {
 			return nil
 		}
@@ -308,18 +589,256 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	return expression
 }
 
+// parseTernaryExpression 解析cond ? a : b。冒号之前的部分可以是任意表达式，
+// 冒号之后的部分使用比TERNARY低一级的优先级解析，使a ? b : c ? d : e被解析为a ? b : (c ? d : e)
+func (p *Parser) parseTernaryExpression(condition ast.Expression) ast.Expression {
+	defer p.untrace(p.trace("parseTernaryExpression"))
+	expression := &ast.TernaryExpression{Token: p.curToken, Condition: condition}
+	question := p.curToken
+	p.nextToken()
+	expression.Consequence = p.parseExpression(LOWEST)
+	if !p.peerTokenIs(token.COLON) {
+		p.peekError(token.COLON).Hint = fmt.Sprintf("to match ? at %s", question.Pos)
+		return nil
+	}
+	p.nextToken()
+	p.nextToken()
+	expression.Alternative = p.parseExpression(TERNARY - 1)
+	return expression
+}
+
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
+	defer p.untrace(p.trace("parseBlockStatement"))
 	blockStatements := &ast.BlockStatement{Token: p.curToken} //设置左大括号为该语法单元的词法标记
 	p.nextToken()
 	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
+		start := p.curToken
 		statement := p.parseStatement()
+		if p.panicMode {
+			p.synchronize(start, true)
+			continue
+		}
 		blockStatements.Statements = append(blockStatements.Statements, statement)
 		p.nextToken()
 	}
+	if p.curTokenIs(token.RBRACE) {
+		blockStatements.Rbrace = p.curToken
+	}
 	return blockStatements
 }
 
+func (p *Parser) parseWhileExpression() ast.Expression {
+	defer p.untrace(p.trace("parseWhileExpression"))
+	expression := &ast.WhileExpression{Token: p.curToken}
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	lparen := p.curToken
+	p.nextToken()
+	expression.Condition = p.parseExpression(LOWEST)
+	if !p.expectClosing(token.RPAREN, lparen) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	p.openScope() //循环体位于每一次迭代各自的作用域中
+	expression.Body = p.parseLoopBody()
+	p.closeScope()
+	return expression
+}
+
+func (p *Parser) parseForInExpression() ast.Expression {
+	defer p.untrace(p.trace("parseForInExpression"))
+	expression := &ast.ForInExpression{Token: p.curToken}
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	lparen := p.curToken
+	if token.IsKeyword(p.peerToken) {
+		p.keywordAsNameError(p.peerToken)
+		return nil
+	}
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	expression.Variable = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	if !p.expectPeek(token.IN) {
+		return nil
+	}
+	p.nextToken()
+	expression.Iterable = p.parseExpression(LOWEST)
+	if !p.expectClosing(token.RPAREN, lparen) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	p.openScope() //循环变量位于每一次迭代各自的作用域中
+	p.declare(expression.Variable, false)
+	expression.Body = p.parseLoopBody()
+	p.closeScope()
+	return expression
+}
+
+func (p *Parser) parseMatchExpression() ast.Expression {
+	defer p.untrace(p.trace("parseMatchExpression"))
+	expression := &ast.MatchExpression{Token: p.curToken}
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	lparen := p.curToken
+	p.nextToken()
+	expression.Subject = p.parseExpression(LOWEST)
+	if !p.expectClosing(token.RPAREN, lparen) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	lbrace := p.curToken
+	for !p.peerTokenIs(token.RBRACE) { //分支之间用逗号分隔，允许最后一个分支之后带有逗号
+		p.nextToken()
+		arm := p.parseMatchArm()
+		if arm == nil {
+			return nil
+		}
+		expression.Arms = append(expression.Arms, arm)
+		if !p.peerTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	if !p.expectClosing(token.RBRACE, lbrace) {
+		return nil
+	}
+	expression.Rbrace = p.curToken
+	if len(expression.Arms) == 0 {
+		p.tokenError(expression.Token, "match expression has no arms")
+		return nil
+	}
+	return expression
+}
+
+// parseMatchArm 解析pattern [if guard] => body，模式中绑定的名称只在guard和body中可见
+func (p *Parser) parseMatchArm() *ast.MatchArm {
+	defer p.untrace(p.trace("parseMatchArm"))
+	arm := &ast.MatchArm{Pattern: p.parseExpression(LOWEST)}
+	if arm.Pattern == nil || p.panicMode {
+		return nil
+	}
+	bindings := map[string]*ast.Identifier{}
+	if !p.checkPattern(arm.Pattern, bindings) {
+		return nil
+	}
+
+	p.openScope()
+	defer p.closeScope()
+	for _, name := range bindings {
+		p.declare(name, false)
+	}
+
+	if p.peerTokenIs(token.IF) {
+		p.nextToken()
+		p.nextToken()
+		arm.Guard = p.parseExpression(LOWEST)
+	}
+	if !p.expectPeek(token.ARROW) {
+		return nil
+	}
+	p.nextToken()
+	if p.curTokenIs(token.LBRACE) { //=>之后的{总是代码块的开始，哈希表字面量需要写在括号中
+		inArmBlock := p.inArmBlock
+		p.inArmBlock = true
+		arm.Body = p.parseBlockStatement()
+		p.inArmBlock = inArmBlock
+		return arm
+	}
+	body := p.parseExpression(LOWEST)
+	if body == nil {
+		return nil
+	}
+	arm.Body = body
+	return arm
+}
+
+// checkPattern 检查表达式是否能作为模式使用，并收集其中绑定的名称，同一个模式中不能重复绑定同一个名称
+func (p *Parser) checkPattern(pattern ast.Expression, bindings map[string]*ast.Identifier) bool {
+	switch pattern := pattern.(type) {
+	case *ast.IntegerLiteral, *ast.FloatLiteral, *ast.StringLiteral, *ast.Boolean:
+		return true
+	case *ast.PrefixExpression: //负数字面量
+		switch pattern.Right.(type) {
+		case *ast.IntegerLiteral, *ast.FloatLiteral:
+			if pattern.Operator == "-" {
+				return true
+			}
+		}
+	case *ast.Identifier:
+		if pattern.Value == "_" {
+			return true
+		}
+		if _, ok := bindings[pattern.Value]; ok {
+			p.tokenError(pattern.Token, "duplicate binding %s in pattern", pattern.Value)
+			return false
+		}
+		bindings[pattern.Value] = pattern
+		return true
+	case *ast.ArrayLiteral:
+		for _, element := range pattern.Elements {
+			if !p.checkPattern(element, bindings) {
+				return false
+			}
+		}
+		return true
+	case *ast.HashLiteral:
+		for _, key := range pattern.Keys { //按照源代码中的顺序检查，保证错误信息稳定
+			switch key.(type) {
+			case *ast.IntegerLiteral, *ast.StringLiteral, *ast.Boolean:
+			default:
+				d := p.spanError(key.Pos(), key.End(), "invalid hash pattern key: %s", key.String())
+				d.Hint = "keys in hash patterns must be string, integer or boolean literals"
+				return false
+			}
+			if !p.checkPattern(pattern.Pairs[key], bindings) {
+				return false
+			}
+		}
+		return true
+	}
+	d := p.spanError(pattern.Pos(), pattern.End(), "invalid pattern: %s", pattern.String())
+	d.Hint = "patterns can be literals, identifiers, _, or arrays and hashes of patterns"
+	return false
+}
+
+// parseLoopBody 解析循环体，循环体内允许出现break和continue
+func (p *Parser) parseLoopBody() *ast.BlockStatement {
+	p.loopDepth++
+	defer func() { p.loopDepth-- }()
+	return p.parseBlockStatement()
+}
+
+// parseLoopControlStatement 解析break和continue语句，它们只能出现在循环体中
+func (p *Parser) parseLoopControlStatement() ast.Statement {
+	defer p.untrace(p.trace("parseLoopControlStatement"))
+	tok := p.curToken
+	if p.loopDepth == 0 {
+		p.tokenError(tok, "%s outside of loop", tok.Literal)
+		return nil
+	}
+	if p.peerTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	if tok.Type == token.BREAK {
+		return &ast.BreakStatement{Token: tok}
+	}
+	return &ast.ContinueStatement{Token: tok}
+}
+
 func (p *Parser) parseFunctionLiteral() ast.Expression {
+	defer p.untrace(p.trace("parseFunctionLiteral"))
 	lit := &ast.FunctionLiteral{Token: p.curToken}
 	if !p.expectPeek(token.LPAREN) {
 		return nil
@@ -330,12 +849,21 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 		return nil
 	}
 
+	loopDepth := p.loopDepth
+	p.loopDepth = 0 //函数体中的break和continue不能跳出定义函数时所在的循环
+	p.openScope()
+	for _, param := range lit.Parameters {
+		p.declare(param, false)
+	}
 	lit.Body = p.parseBlockStatement()
+	p.closeScope()
+	p.loopDepth = loopDepth
 
 	return lit
 }
 
 func (p *Parser) parseFunctionParameters() []*ast.Identifier {
+	lparen := p.curToken
 	var ids []*ast.Identifier
 	if p.peerTokenIs(token.RPAREN) {
 		p.nextToken()
@@ -343,48 +871,128 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 	}
 	p.nextToken()
 
-	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	ident := p.parseParameter()
+	if ident == nil {
+		return nil
+	}
 	ids = append(ids, ident)
 
 	for p.peerTokenIs(token.COMMA) {
 		p.nextToken()
 		p.nextToken()
-		ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		ident := p.parseParameter()
+		if ident == nil {
+			return nil
+		}
 		ids = append(ids, ident)
 	}
 
-	if !p.expectPeek(token.RPAREN) {
+	if !p.expectClosing(token.RPAREN, lparen) {
 		return nil
 	}
 
 	return ids
 }
 
+// parseParameter 解析一个形参，形参必须是标识符
+func (p *Parser) parseParameter() *ast.Identifier {
+	if token.IsKeyword(p.curToken) {
+		p.keywordAsNameError(p.curToken)
+		return nil
+	}
+	if !p.curTokenIs(token.IDENT) {
+		d := p.tokenError(p.curToken, "expected parameter name to be IDENT, got %s instead", p.curToken.Type)
+		d.Hint = "function parameters must be identifiers"
+		return nil
+	}
+	return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+}
+
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
+	defer p.untrace(p.trace("parseCallExpression"))
 	exp := &ast.CallExpression{Token: p.curToken, Function: function}
-	exp.Arguments = p.parseCallArguments()
+	exp.Arguments = p.parseExpressionList(token.RPAREN)
+	if p.curTokenIs(token.RPAREN) {
+		exp.Rparen = p.curToken
+	}
 	return exp
 }
 
-func (p *Parser) parseCallArguments() []ast.Expression {
-	args := []ast.Expression{}
+func (p *Parser) parseArrayLiteral() ast.Expression {
+	defer p.untrace(p.trace("parseArrayLiteral"))
+	array := &ast.ArrayLiteral{Token: p.curToken}
+	array.Elements = p.parseExpressionList(token.RBRACKET)
+	if p.curTokenIs(token.RBRACKET) {
+		array.Rbracket = p.curToken
+	}
+	return array
+}
+
+// parseExpressionList 解析以逗号分隔、以end结尾的表达式列表，调用参数和数组元素都使用这个函数
+func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
+	open := p.curToken
+	list := []ast.Expression{}
 
-	if p.peerTokenIs(token.RPAREN) { //空参列表的边界调用
+	if p.peerTokenIs(end) { //空列表的边界情况
 		p.nextToken()
-		return args
+		return list
 	}
 
 	p.nextToken()
-	args = append(args, p.parseExpression(LOWEST))
+	list = append(list, p.parseExpression(LOWEST))
 
-	for p.peerTokenIs(token.COMMA) { //判断是否符合参数列表的条件要求
+	for p.peerTokenIs(token.COMMA) { //判断是否符合列表的条件要求
 		p.nextToken()
 		p.nextToken()
-		args = append(args, p.parseExpression(LOWEST))
+		list = append(list, p.parseExpression(LOWEST))
 	}
-	if !p.expectPeek(token.RPAREN) {
+	if !p.expectClosing(end, open) {
 		return nil
 	} //如果不是，那么就返回nil
-	return args
+	return list
+}
+
+func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
+	defer p.untrace(p.trace("parseIndexExpression"))
+	exp := &ast.IndexExpression{Token: p.curToken, Left: left}
+
+	p.nextToken()
+	exp.Index = p.parseExpression(LOWEST)
+
+	if !p.expectClosing(token.RBRACKET, exp.Token) {
+		return nil
+	}
+	exp.Rbracket = p.curToken
+	return exp
+}
+
+func (p *Parser) parseHashLiteral() ast.Expression {
+	defer p.untrace(p.trace("parseHashLiteral"))
+	hash := &ast.HashLiteral{Token: p.curToken}
+	hash.Pairs = make(map[ast.Expression]ast.Expression)
 
+	for !p.peerTokenIs(token.RBRACE) {
+		p.nextToken()
+		key := p.parseExpression(LOWEST)
+
+		if !p.expectPeek(token.COLON) {
+			return nil
+		}
+
+		p.nextToken()
+		value := p.parseExpression(LOWEST)
+
+		hash.Pairs[key] = value
+		hash.Keys = append(hash.Keys, key)
+
+		if !p.peerTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) { //键值对之间必须用逗号分隔
+			return nil
+		}
+	}
+
+	if !p.expectClosing(token.RBRACE, hash.Token) {
+		return nil
+	}
+	hash.Rbrace = p.curToken
+	return hash
 }
@@ -0,0 +1,58 @@
+package parser
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/token"
+)
+
+// binding 记录解析过程中见到的一个绑定，用于在解析阶段发现对常量的重新赋值
+type binding struct {
+	constant bool
+	pos      token.Position //声明的位置
+}
+
+// scope 与求值时的作用域一一对应：程序的最外层、函数体、for和while循环的每一次迭代以及match的每一个分支各自是一个作用域，
+// if的代码块与外层共享同一个作用域
+type scope map[string]binding
+
+func (p *Parser) openScope() {
+	p.scopes = append(p.scopes, scope{})
+}
+
+func (p *Parser) closeScope() {
+	p.scopes = p.scopes[:len(p.scopes)-1]
+}
+
+func (p *Parser) declare(name *ast.Identifier, constant bool) {
+	p.scopes[len(p.scopes)-1][name.Value] = binding{constant: constant, pos: name.Pos()}
+}
+
+// checkRedeclaration 检查name是否会在当前作用域中覆盖一个常量
+func (p *Parser) checkRedeclaration(name token.Token) bool {
+	b, ok := p.scopes[len(p.scopes)-1][name.Literal]
+	if !ok || !b.constant {
+		return true
+	}
+	d := p.tokenError(name, "cannot redeclare constant %s", name.Literal)
+	d.Hint = fmt.Sprintf("%s is declared as a constant at %s", name.Literal, b.pos)
+	return false
+}
+
+// checkConstAssignment 检查赋值的目标是否是一个常量，只能发现在解析到赋值时已经声明过的常量，
+// 其余的情况（例如在函数中给之后才声明的常量赋值）由求值器在运行时检查
+func (p *Parser) checkConstAssignment(target *ast.Identifier) bool {
+	for i := len(p.scopes) - 1; i >= 0; i-- {
+		b, ok := p.scopes[i][target.Value]
+		if !ok {
+			continue
+		}
+		if !b.constant {
+			return true
+		}
+		d := p.tokenError(target.Token, "cannot assign to constant %s", target.Value)
+		d.Hint = fmt.Sprintf("%s is declared as a constant at %s", target.Value, b.pos)
+		return false
+	}
+	return true
+}
@@ -1,9 +1,13 @@
 package parser
 
 import (
+	"bytes"
 	"fmt"
 	"monkey/ast"
+	"monkey/diagnostic"
 	"monkey/lexer"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -49,7 +53,7 @@ func checkParserErrors(t *testing.T, p *Parser) {
 
 	t.Errorf("parser has %d errors", len(errors))
 	for _, msg := range errors {
-		t.Errorf("parser error: %q", msg)
+		t.Errorf("parser error: %q", msg.String())
 	}
 	t.FailNow()
 }
@@ -80,6 +84,77 @@ func testLetStatement(t *testing.T, s ast.Statement, name string) bool {
 	return true
 }
 
+func TestConstStatements(t *testing.T) {
+	input := `const answer = 42; const name = "monkey";`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements does not contain 2 statements. got=%d", len(program.Statements))
+	}
+	stmt, ok := program.Statements[0].(*ast.ConstStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *ast.ConstStatement. got=%T", program.Statements[0])
+	}
+	if stmt.Name.Value != "answer" {
+		t.Errorf("stmt.Name.Value not %q. got=%q", "answer", stmt.Name.Value)
+	}
+	testIntegerLiteral(t, stmt.Value, 42)
+	if program.String() != "const answer = 42;const name = monkey;" {
+		t.Errorf("program.String() wrong. got=%q", program.String())
+	}
+}
+
+func TestConstViolations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"const x = 1; x = 2;", []string{"1:14: cannot assign to constant x"}},
+		{"const x = 1; x += 2;", []string{"1:14: cannot assign to constant x"}},
+		{"const x = 1; let x = 2;", []string{"1:18: cannot redeclare constant x"}},
+		{"const x = 1; const x = 2;", []string{"1:20: cannot redeclare constant x"}},
+		{"const x = 1; let f = fn() { x = 2 };", []string{"1:29: cannot assign to constant x"}},
+		{"const x = 1; while (true) { x = 2 }", []string{"1:29: cannot assign to constant x"}},
+		{"const x = 1; x = 2; let y = 3; const y = 4; y = 5", []string{
+			"1:14: cannot assign to constant x",
+			"1:45: cannot assign to constant y",
+		}},
+		//以下情况在解析阶段是合法的
+		{"const x = 1; let f = fn() { let x = 2; x = 3 };", nil},
+		{"const x = 1; let f = fn(x) { x = 3 };", nil},
+		{"const x = 1; for (x in [1]) { x = 3 }", nil},
+		{"let i = 0; while (i < 3) { const k = i; i += 1 }", nil}, //while的每一次迭代也是新的作用域
+		{"const x = 1; while (true) { let x = 2; x = 3; break }", nil},
+		{"let x = 1; const x = 2;", nil},
+		{"const xs = [1]; xs[0] = 2;", nil},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) != len(tt.expected) {
+			t.Errorf("input %q: expected %d errors, got=%d (%v)", tt.input, len(tt.expected), len(errors), errors)
+			continue
+		}
+		for i, msg := range tt.expected {
+			if errors[i].String() != msg {
+				t.Errorf("wrong error. expected=%q, got=%q", msg, errors[i].String())
+			}
+		}
+	}
+
+	p := New(lexer.New("const limit = 10;\nlimit = 20;"))
+	p.ParseProgram()
+	if len(p.Errors()) != 1 || p.Errors()[0].Hint != "limit is declared as a constant at 1:7" {
+		t.Errorf("wrong hint. got=%v", p.Errors())
+	}
+}
+
 func TestReturnStatements(t *testing.T) {
 	tests := []struct {
 		input         string
@@ -176,6 +251,144 @@ func TestIntegerLiteralExpression(t *testing.T) {
 	}
 }
 
+func TestIntegerLiteralFormats(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"0xff", 255},
+		{"0XFF", 255},
+		{"0o17", 15},
+		{"0b1010", 10},
+		{"1_000_000", 1000000},
+		{"0x_dead_beef", 0xdeadbeef},
+		{"0", 0},
+		{"0o10", 8},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		literal, ok := stmt.Expression.(*ast.IntegerLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.IntegerLiteral. got=%T", stmt.Expression)
+		}
+		if literal.Value != tt.expected {
+			t.Errorf("literal.Value not %d. got=%d", tt.expected, literal.Value)
+		}
+	}
+}
+
+func TestFloatLiteralExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"3.14", 3.14},
+		{"0.5", 0.5},
+		{"1e9", 1e9},
+		{"2.5E-3", 2.5e-3},
+		{"6e+2", 600},
+		{"1_000.000_1", 1000.0001},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		literal, ok := stmt.Expression.(*ast.FloatLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.FloatLiteral. got=%T", stmt.Expression)
+		}
+		if literal.Value != tt.expected {
+			t.Errorf("literal.Value not %g. got=%g", tt.expected, literal.Value)
+		}
+		if literal.String() != tt.input {
+			t.Errorf("literal.String() not %q. got=%q", tt.input, literal.String())
+		}
+	}
+}
+
+func TestInvalidNumberLiterals(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"1__0", `could not parse "1__0" as integer`},
+		{"1_", `could not parse "1_" as integer`},
+		{"0b12", `could not parse "0b12" as integer`},
+		{"0x", `could not parse "0x" as integer`},
+		{"1e", `could not parse "1e" as float`},
+		{"1e400", `could not parse "1e400" as float`},
+		{"9223372036854775808", `could not parse "9223372036854775808" as integer`},
+		{"010", `leading zeros are not allowed in integer literal "010"`}, //八进制数必须使用0o前缀
+		{"09", `leading zeros are not allowed in integer literal "09"`},
+		{"0_7", `leading zeros are not allowed in integer literal "0_7"`},
+		{"00", `leading zeros are not allowed in integer literal "00"`},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) != 1 {
+			t.Errorf("input %q: expected 1 error, got=%d", tt.input, len(errors))
+			continue
+		}
+		if errors[0].Message != tt.expectedMessage {
+			t.Errorf("wrong message. expected=%q, got=%q", tt.expectedMessage, errors[0].Message)
+		}
+	}
+}
+
+func TestLeadingZeroHint(t *testing.T) {
+	tests := []struct {
+		input string
+		hint  string
+	}{
+		{"010", "write 10 for a decimal number, or use the 0o prefix for an octal number"},
+		{"0_0", "write 0 for a decimal number, or use the 0o prefix for an octal number"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) != 1 {
+			t.Fatalf("input %q: expected 1 error, got=%d", tt.input, len(errors))
+		}
+		if errors[0].Hint != tt.hint {
+			t.Errorf("wrong hint. expected=%q, got=%q", tt.hint, errors[0].Hint)
+		}
+	}
+}
+
+func TestStringLiteralExpression(t *testing.T) {
+	input := `"hello world";`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	literal, ok := stmt.Expression.(*ast.StringLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.StringLiteral. got=%T", stmt.Expression)
+	}
+
+	if literal.Value != "hello world" {
+		t.Errorf("literal.Value not %q. got=%q", "hello world", literal.Value)
+	}
+}
+
 func TestParsingPrefixExpressions(t *testing.T) {
 	prefixTests := []struct {
 		input    string
@@ -275,6 +488,16 @@ func TestParsingInfixExpressions(t *testing.T) {
 		{"5 < 5;", 5, "<", 5},
 		{"5 == 5;", 5, "==", 5},
 		{"5 != 5;", 5, "!=", 5},
+		{"5 <= 5;", 5, "<=", 5},
+		{"5 >= 5;", 5, ">=", 5},
+		{"5 % 5;", 5, "%", 5},
+		{"5 & 5;", 5, "&", 5},
+		{"5 | 5;", 5, "|", 5},
+		{"5 ^ 5;", 5, "^", 5},
+		{"5 << 5;", 5, "<<", 5},
+		{"5 >> 5;", 5, ">>", 5},
+		{"true && false", true, "&&", false},
+		{"true || false", true, "||", false},
 		//{"foobar + barfoo;", "foobar", "+", "barfoo"},
 		//{"foobar - barfoo;", "foobar", "-", "barfoo"},
 		//{"foobar * barfoo;", "foobar", "*", "barfoo"},
@@ -465,6 +688,78 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"!(true == true)",
 			"(!(true == true))",
 		},
+		{
+			"a * [1, 2, 3, 4][b * c] * d",
+			"((a * ([1, 2, 3, 4][(b * c)])) * d)",
+		},
+		{
+			"add(a * b[2], b[1], 2 * [1, 2][1])",
+			"add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
+		},
+		{
+			"a || b && c",
+			"(a || (b && c))",
+		},
+		{
+			"a == b && c != d || e",
+			"(((a == b) && (c != d)) || e)",
+		},
+		{
+			"a <= b == c >= d",
+			"((a <= b) == (c >= d))",
+		},
+		{
+			"a & b | c ^ d",
+			"((a & b) | (c ^ d))",
+		},
+		{
+			"a | b && c & d == e",
+			"((a | b) && (c & (d == e)))",
+		},
+		{
+			"a << b + c < d >> e",
+			"((a << (b + c)) < (d >> e))",
+		},
+		{
+			"a % b * c + d",
+			"(((a % b) * c) + d)",
+		},
+		{
+			"~a & -b",
+			"((~a) & (-b))",
+		},
+		{
+			"a = b = c",
+			"(a = (b = c))",
+		},
+		{
+			"a ? b : c ? d : e",
+			"(a ? b : (c ? d : e))",
+		},
+		{
+			"a || b ? c + 1 : d && e",
+			"((a || b) ? (c + 1) : (d && e))",
+		},
+		{
+			"x = a ? b : c",
+			"(x = (a ? b : c))",
+		},
+		{
+			"a ? b ? c : d : e",
+			"(a ? (b ? c : d) : e)",
+		},
+		{
+			"f(a ? b : c, d)",
+			"f((a ? b : c), d)",
+		},
+		{
+			"a += b * c || d",
+			"(a += ((b * c) || d))",
+		},
+		{
+			"a[i + 1] -= f(x)",
+			"((a[(i + 1)]) -= f(x))",
+		},
 		//{
 		//	"a + add(b * c) + d",
 		//	"((a + add((b * c))) + d)",
@@ -578,8 +873,8 @@ func TestIfExpression(t *testing.T) {
 	}
 }
 
-func TestFunctionLiteralParsing(t *testing.T) {
-	input := `fn(x, y) { x + y; }`
+func TestWhileExpression(t *testing.T) {
+	input := `while (x < y) { x; break; continue }`
 
 	l := lexer.New(input)
 	p := New(l)
@@ -590,43 +885,34 @@ func TestFunctionLiteralParsing(t *testing.T) {
 		t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
 			1, len(program.Statements))
 	}
-
 	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
 	if !ok {
 		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
 			program.Statements[0])
 	}
-
-	function, ok := stmt.Expression.(*ast.FunctionLiteral)
+	exp, ok := stmt.Expression.(*ast.WhileExpression)
 	if !ok {
-		t.Fatalf("stmt.Expression is not ast.FunctionLiteral. got=%T",
-			stmt.Expression)
+		t.Fatalf("stmt.Expression is not ast.WhileExpression. got=%T", stmt.Expression)
 	}
-
-	if len(function.Parameters) != 2 {
-		t.Fatalf("function literal parameters wrong. want 2, got=%d\n",
-			len(function.Parameters))
+	if !testInfixExpression(t, exp.Condition, "x", "<", "y") {
+		return
 	}
-
-	testLiteralExpression(t, function.Parameters[0], "x")
-	testLiteralExpression(t, function.Parameters[1], "y")
-
-	if len(function.Body.Statements) != 1 {
-		t.Fatalf("function.Body.Statements has not 1 statements. got=%d\n",
-			len(function.Body.Statements))
+	if len(exp.Body.Statements) != 3 {
+		t.Fatalf("body is not 3 statements. got=%d\n", len(exp.Body.Statements))
 	}
-
-	bodyStmt, ok := function.Body.Statements[0].(*ast.ExpressionStatement)
-	if !ok {
-		t.Fatalf("function body stmt is not ast.ExpressionStatement. got=%T",
-			function.Body.Statements[0])
+	if _, ok := exp.Body.Statements[1].(*ast.BreakStatement); !ok {
+		t.Errorf("Statements[1] is not ast.BreakStatement. got=%T", exp.Body.Statements[1])
+	}
+	if _, ok := exp.Body.Statements[2].(*ast.ContinueStatement); !ok {
+		t.Errorf("Statements[2] is not ast.ContinueStatement. got=%T", exp.Body.Statements[2])
+	}
+	if program.String() != "while(x < y) xbreak;continue;" {
+		t.Errorf("program.String() wrong. got=%q", program.String())
 	}
-
-	testInfixExpression(t, bodyStmt.Expression, "x", "+", "y")
 }
 
-func TestCallExpressionParsing(t *testing.T) {
-	input := "add(1, 2 * 3, 4 + 5);"
+func TestForInExpression(t *testing.T) {
+	input := `for (item in [1, 2]) { item }`
 
 	l := lexer.New(input)
 	p := New(l)
@@ -637,53 +923,65 @@ func TestCallExpressionParsing(t *testing.T) {
 		t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
 			1, len(program.Statements))
 	}
-
-	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.ForInExpression)
 	if !ok {
-		t.Fatalf("stmt is not ast.ExpressionStatement. got=%T",
-			program.Statements[0])
+		t.Fatalf("stmt.Expression is not ast.ForInExpression. got=%T", stmt.Expression)
 	}
-
-	exp, ok := stmt.Expression.(*ast.CallExpression)
-	if !ok {
-		t.Fatalf("stmt.Expression is not ast.CallExpression. got=%T",
-			stmt.Expression)
+	if exp.Variable.Value != "item" {
+		t.Errorf("exp.Variable is not %q. got=%q", "item", exp.Variable.Value)
 	}
-
-	if !testIdentifier(t, exp.Function, "add") {
-		return
+	if exp.Iterable.String() != "[1, 2]" {
+		t.Errorf("exp.Iterable is not %q. got=%q", "[1, 2]", exp.Iterable.String())
 	}
+	if len(exp.Body.Statements) != 1 {
+		t.Fatalf("body is not 1 statements. got=%d\n", len(exp.Body.Statements))
+	}
+	body := exp.Body.Statements[0].(*ast.ExpressionStatement)
+	testIdentifier(t, body.Expression, "item")
+}
 
-	if len(exp.Arguments) != 3 {
-		t.Fatalf("wrong length of arguments. got=%d", len(exp.Arguments))
+func TestLoopErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"break;", "1:1: break outside of loop"},
+		{"if (true) { continue }", "1:13: continue outside of loop"},
+		{"while (true) { fn() { break } }", "1:23: break outside of loop"},
+		{"for (x [1]) { x }", "1:8: expected next token to be IN, got [ instead"},
+		{"for (while in [1]) { 1 }", `1:6: cannot use keyword "while" as a name`},
+		{"while (true { 1 }", "1:13: expected next token to be ), got { instead"},
 	}
 
-	testLiteralExpression(t, exp.Arguments[0], 1)
-	testInfixExpression(t, exp.Arguments[1], 2, "*", 3)
-	testInfixExpression(t, exp.Arguments[2], 4, "+", 5)
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) != 1 {
+			t.Errorf("input %q: expected 1 error, got=%d", tt.input, len(errors))
+			continue
+		}
+		if errors[0].String() != tt.expected {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expected, errors[0].String())
+		}
+	}
 }
 
-func TestCallExpressionParameterParsing(t *testing.T) {
+func TestAssignExpression(t *testing.T) {
 	tests := []struct {
-		input         string
-		expectedIdent string
-		expectedArgs  []string
+		input          string
+		expectedTarget string
+		operator       string
+		expectedValue  string
 	}{
-		{
-			input:         "add();",
-			expectedIdent: "add",
-			expectedArgs:  []string{},
-		},
-		{
-			input:         "add(1);",
-			expectedIdent: "add",
-			expectedArgs:  []string{"1"},
-		},
-		{
-			input:         "add(1, 2 * 3, 4 + 5);",
-			expectedIdent: "add",
-			expectedArgs:  []string{"1", "(2 * 3)", "(4 + 5)"},
-		},
+		{"x = 5;", "x", "=", "5"},
+		{"x += y", "x", "+=", "y"},
+		{"x -= 1", "x", "-=", "1"},
+		{"x *= 2", "x", "*=", "2"},
+		{"x /= 2", "x", "/=", "2"},
+		{"arr[0] = 5", "(arr[0])", "=", "5"},
+		{`h["k"] = v`, "(h[k])", "=", "v"},
 	}
 
 	for _, tt := range tests {
@@ -692,27 +990,927 @@ func TestCallExpressionParameterParsing(t *testing.T) {
 		program := p.ParseProgram()
 		checkParserErrors(t, p)
 
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statements. got=%d", len(program.Statements))
+		}
 		stmt := program.Statements[0].(*ast.ExpressionStatement)
-		exp, ok := stmt.Expression.(*ast.CallExpression)
+		exp, ok := stmt.Expression.(*ast.AssignExpression)
 		if !ok {
-			t.Fatalf("stmt.Expression is not ast.CallExpression. got=%T",
-				stmt.Expression)
+			t.Fatalf("stmt.Expression is not ast.AssignExpression. got=%T", stmt.Expression)
 		}
-
-		if !testIdentifier(t, exp.Function, tt.expectedIdent) {
-			return
+		if exp.Target.String() != tt.expectedTarget {
+			t.Errorf("exp.Target is not %q. got=%q", tt.expectedTarget, exp.Target.String())
 		}
-
-		if len(exp.Arguments) != len(tt.expectedArgs) {
-			t.Fatalf("wrong number of arguments. want=%d, got=%d",
-				len(tt.expectedArgs), len(exp.Arguments))
+		if exp.Operator != tt.operator {
+			t.Errorf("exp.Operator is not %q. got=%q", tt.operator, exp.Operator)
+		}
+		if exp.Value.String() != tt.expectedValue {
+			t.Errorf("exp.Value is not %q. got=%q", tt.expectedValue, exp.Value.String())
 		}
+	}
+}
 
-		for i, arg := range tt.expectedArgs {
-			if exp.Arguments[i].String() != arg {
-				t.Errorf("argument %d wrong. want=%q, got=%q", i,
-					arg, exp.Arguments[i].String())
-			}
+func TestInvalidAssignmentTargets(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 = 2", "1:1: invalid assignment target: 1"},
+		{"a + b = c", "1:1: invalid assignment target: (a + b)"},
+		{"f() += 1", "1:1: invalid assignment target: f()"},
+		{"let x = 1; -x = 2", "1:12: invalid assignment target: (-x)"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) != 1 {
+			t.Errorf("input %q: expected 1 error, got=%d", tt.input, len(errors))
+			continue
 		}
+		if errors[0].String() != tt.expected {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expected, errors[0].String())
+		}
+	}
+}
+
+func TestElseIfExpression(t *testing.T) {
+	input := `if (x < y) { x } else if (x > y) { y } else { z }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
+			1, len(program.Statements))
+	}
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.IfExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.IfExpression. got=%T", stmt.Expression)
 	}
+	if exp.Alternative == nil || len(exp.Alternative.Statements) != 1 {
+		t.Fatalf("exp.Alternative does not contain exactly one statement. got=%+v", exp.Alternative)
+	}
+	alternative, ok := exp.Alternative.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("Alternative.Statements[0] is not ast.ExpressionStatement. got=%T", exp.Alternative.Statements[0])
+	}
+	nested, ok := alternative.Expression.(*ast.IfExpression)
+	if !ok {
+		t.Fatalf("else branch is not ast.IfExpression. got=%T", alternative.Expression)
+	}
+	if !testInfixExpression(t, nested.Condition, "x", ">", "y") {
+		return
+	}
+	if nested.Alternative == nil || nested.Alternative.String() != "z" {
+		t.Errorf("nested.Alternative is not z. got=%+v", nested.Alternative)
+	}
+	if exp.End().Offset != len(input) {
+		t.Errorf("exp.End() wrong. expected offset %d, got=%d", len(input), exp.End().Offset)
+	}
+}
+
+func TestTernaryExpression(t *testing.T) {
+	input := `x < y ? x : y`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.TernaryExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.TernaryExpression. got=%T", stmt.Expression)
+	}
+	if !testInfixExpression(t, exp.Condition, "x", "<", "y") {
+		return
+	}
+	testIdentifier(t, exp.Consequence, "x")
+	testIdentifier(t, exp.Alternative, "y")
+
+	p = New(lexer.New("let m = a ? b;"))
+	p.ParseProgram()
+	errors := p.Errors()
+	if len(errors) != 1 {
+		t.Fatalf("expected 1 error, got=%d", len(errors))
+	}
+	if errors[0].String() != "1:14: expected next token to be :, got ; instead" || errors[0].Hint != "to match ? at 1:11" {
+		t.Errorf("wrong error. got=%q (hint %q)", errors[0].String(), errors[0].Hint)
+	}
+}
+
+func TestMatchExpression(t *testing.T) {
+	input := `match (v) {
+	1 => "one",
+	-2.5 => "negative",
+	[a, _] if a > 0 => a,
+	{"type": t} => t,
+	_ => null_value,
+}`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statements. got=%d", len(program.Statements))
+	}
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.MatchExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.MatchExpression. got=%T", stmt.Expression)
+	}
+	testIdentifier(t, exp.Subject, "v")
+
+	expected := []struct {
+		pattern string
+		guard   string
+		body    string
+	}{
+		{"1", "", "one"},
+		{"(-2.5)", "", "negative"},
+		{"[a, _]", "(a > 0)", "a"},
+		{"{type:t}", "", "t"},
+		{"_", "", "null_value"},
+	}
+	if len(exp.Arms) != len(expected) {
+		t.Fatalf("exp.Arms does not contain %d arms. got=%d", len(expected), len(exp.Arms))
+	}
+	for i, arm := range exp.Arms {
+		if arm.Pattern.String() != expected[i].pattern {
+			t.Errorf("arms[%d] - pattern wrong. expected=%q, got=%q", i, expected[i].pattern, arm.Pattern.String())
+		}
+		guard := ""
+		if arm.Guard != nil {
+			guard = arm.Guard.String()
+		}
+		if guard != expected[i].guard {
+			t.Errorf("arms[%d] - guard wrong. expected=%q, got=%q", i, expected[i].guard, guard)
+		}
+		if arm.Body.String() != expected[i].body {
+			t.Errorf("arms[%d] - body wrong. expected=%q, got=%q", i, expected[i].body, arm.Body.String())
+		}
+	}
+	if exp.End().Offset != len(input) {
+		t.Errorf("exp.End() wrong. expected offset %d, got=%d", len(input), exp.End().Offset)
+	}
+}
+
+func TestInvalidMatchExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"match (v) { }", "1:1: match expression has no arms"},
+		{"match (v) { 1 + 2 => 3 }", "1:13: invalid pattern: (1 + 2)"},
+		{"match (v) { f(x) => 3 }", "1:13: invalid pattern: f(x)"},
+		{"match (v) { [a, a] => a }", "1:17: duplicate binding a in pattern"},
+		{"match (v) { {k: 1} => 1 }", "1:14: invalid hash pattern key: k"},
+		{"match (v) { 1 => 2 3 => 4 }", "1:20: expected next token to be ,, got INT instead"},
+		{"match (v) { 1 : 2 }", "1:15: expected next token to be =>, got : instead"},
+		{"match (v) { 1 => 2, ", "1:21: no prefix parse function for EOF found"},
+		{"const c = 1; match (v) { [c] => c = 2 }", ""},
+		{"match (v) { _ => { 1 } }", ""},
+		{`match (v) { _ => ({"k": 1}) }`, ""},
+		{`match (v) { _ => {"k": 1} }`, "1:22: no prefix parse function for : found"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		errors := p.Errors()
+		if tt.expected == "" {
+			if len(errors) != 0 {
+				t.Errorf("input %q: unexpected errors %v", tt.input, errors)
+			}
+			continue
+		}
+		if len(errors) == 0 {
+			t.Errorf("input %q: expected an error", tt.input)
+			continue
+		}
+		if errors[0].String() != tt.expected {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expected, errors[0].String())
+		}
+	}
+}
+
+func TestMatchArmBlockBody(t *testing.T) {
+	input := `match (v) { 1 => { let x = 2; x }, _ => 0 }`
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	exp := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.MatchExpression)
+	block, ok := exp.Arms[0].Body.(*ast.BlockStatement)
+	if !ok {
+		t.Fatalf("arms[0].Body is not ast.BlockStatement. got=%T", exp.Arms[0].Body)
+	}
+	if len(block.Statements) != 2 {
+		t.Fatalf("block does not contain 2 statements. got=%d", len(block.Statements))
+	}
+	if _, ok := exp.Arms[1].Body.(*ast.IntegerLiteral); !ok {
+		t.Errorf("arms[1].Body is not ast.IntegerLiteral. got=%T", exp.Arms[1].Body)
+	}
+
+	//{之后是哈希表字面量时提示加上括号
+	p = New(lexer.New(`match (v) { _ => {"k": 1} }`))
+	p.ParseProgram()
+	errors := p.Errors()
+	if len(errors) == 0 {
+		t.Fatalf("expected an error")
+	}
+	if errors[0].Hint != `a { after => starts a block; wrap hash literals in parentheses: _ => ({"k": 1})` {
+		t.Errorf("wrong hint. got=%q", errors[0].Hint)
+	}
+}
+
+func TestFunctionLiteralParsing(t *testing.T) {
+	input := `fn(x, y) { x + y; }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
+			1, len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
+			program.Statements[0])
+	}
+
+	function, ok := stmt.Expression.(*ast.FunctionLiteral)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.FunctionLiteral. got=%T",
+			stmt.Expression)
+	}
+
+	if len(function.Parameters) != 2 {
+		t.Fatalf("function literal parameters wrong. want 2, got=%d\n",
+			len(function.Parameters))
+	}
+
+	testLiteralExpression(t, function.Parameters[0], "x")
+	testLiteralExpression(t, function.Parameters[1], "y")
+
+	if len(function.Body.Statements) != 1 {
+		t.Fatalf("function.Body.Statements has not 1 statements. got=%d\n",
+			len(function.Body.Statements))
+	}
+
+	bodyStmt, ok := function.Body.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("function body stmt is not ast.ExpressionStatement. got=%T",
+			function.Body.Statements[0])
+	}
+
+	testInfixExpression(t, bodyStmt.Expression, "x", "+", "y")
+}
+
+func TestCallExpressionParsing(t *testing.T) {
+	input := "add(1, 2 * 3, 4 + 5);"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
+			1, len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("stmt is not ast.ExpressionStatement. got=%T",
+			program.Statements[0])
+	}
+
+	exp, ok := stmt.Expression.(*ast.CallExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.CallExpression. got=%T",
+			stmt.Expression)
+	}
+
+	if !testIdentifier(t, exp.Function, "add") {
+		return
+	}
+
+	if len(exp.Arguments) != 3 {
+		t.Fatalf("wrong length of arguments. got=%d", len(exp.Arguments))
+	}
+
+	testLiteralExpression(t, exp.Arguments[0], 1)
+	testInfixExpression(t, exp.Arguments[1], 2, "*", 3)
+	testInfixExpression(t, exp.Arguments[2], 4, "+", 5)
+}
+
+func TestCallExpressionParameterParsing(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedIdent string
+		expectedArgs  []string
+	}{
+		{
+			input:         "add();",
+			expectedIdent: "add",
+			expectedArgs:  []string{},
+		},
+		{
+			input:         "add(1);",
+			expectedIdent: "add",
+			expectedArgs:  []string{"1"},
+		},
+		{
+			input:         "add(1, 2 * 3, 4 + 5);",
+			expectedIdent: "add",
+			expectedArgs:  []string{"1", "(2 * 3)", "(4 + 5)"},
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		exp, ok := stmt.Expression.(*ast.CallExpression)
+		if !ok {
+			t.Fatalf("stmt.Expression is not ast.CallExpression. got=%T",
+				stmt.Expression)
+		}
+
+		if !testIdentifier(t, exp.Function, tt.expectedIdent) {
+			return
+		}
+
+		if len(exp.Arguments) != len(tt.expectedArgs) {
+			t.Fatalf("wrong number of arguments. want=%d, got=%d",
+				len(tt.expectedArgs), len(exp.Arguments))
+		}
+
+		for i, arg := range tt.expectedArgs {
+			if exp.Arguments[i].String() != arg {
+				t.Errorf("argument %d wrong. want=%q, got=%q", i,
+					arg, exp.Arguments[i].String())
+			}
+		}
+	}
+}
+
+func TestParsingArrayLiterals(t *testing.T) {
+	input := "[1, 2 * 2, 3 + 3]"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	array, ok := stmt.Expression.(*ast.ArrayLiteral)
+	if !ok {
+		t.Fatalf("exp not ast.ArrayLiteral. got=%T", stmt.Expression)
+	}
+
+	if len(array.Elements) != 3 {
+		t.Fatalf("len(array.Elements) not 3. got=%d", len(array.Elements))
+	}
+
+	testIntegerLiteral(t, array.Elements[0], 1)
+	testInfixExpression(t, array.Elements[1], 2, "*", 2)
+	testInfixExpression(t, array.Elements[2], 3, "+", 3)
+}
+
+func TestParsingIndexExpressions(t *testing.T) {
+	input := "myArray[1 + 1]"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	indexExp, ok := stmt.Expression.(*ast.IndexExpression)
+	if !ok {
+		t.Fatalf("exp not *ast.IndexExpression. got=%T", stmt.Expression)
+	}
+
+	if !testIdentifier(t, indexExp.Left, "myArray") {
+		return
+	}
+
+	if !testInfixExpression(t, indexExp.Index, 1, "+", 1) {
+		return
+	}
+}
+
+func TestParsingHashLiteralsStringKeys(t *testing.T) {
+	input := `{"one": 1, "two": 2, "three": 3}`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	hash, ok := stmt.Expression.(*ast.HashLiteral)
+	if !ok {
+		t.Fatalf("exp is not ast.HashLiteral. got=%T", stmt.Expression)
+	}
+
+	if len(hash.Pairs) != 3 {
+		t.Errorf("hash.Pairs has wrong length. got=%d", len(hash.Pairs))
+	}
+	keys := []string{}
+	for _, key := range hash.Keys {
+		keys = append(keys, key.String())
+	}
+	if strings.Join(keys, ",") != "one,two,three" { //Keys保存键在源代码中的顺序
+		t.Errorf("hash.Keys wrong. got=%v", keys)
+	}
+
+	expected := map[string]int64{
+		"one":   1,
+		"two":   2,
+		"three": 3,
+	}
+
+	for key, value := range hash.Pairs {
+		literal, ok := key.(*ast.StringLiteral)
+		if !ok {
+			t.Errorf("key is not ast.StringLiteral. got=%T", key)
+		}
+
+		expectedValue := expected[literal.String()]
+
+		testIntegerLiteral(t, value, expectedValue)
+	}
+}
+
+func TestParsingEmptyHashLiteral(t *testing.T) {
+	input := "{}"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	hash, ok := stmt.Expression.(*ast.HashLiteral)
+	if !ok {
+		t.Fatalf("exp is not ast.HashLiteral. got=%T", stmt.Expression)
+	}
+
+	if len(hash.Pairs) != 0 {
+		t.Errorf("hash.Pairs has wrong length. got=%d", len(hash.Pairs))
+	}
+}
+
+func TestParsingHashLiteralsWithExpressions(t *testing.T) {
+	input := `{"one": 0 + 1, "two": 10 - 8, "three": 15 / 5}`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	hash, ok := stmt.Expression.(*ast.HashLiteral)
+	if !ok {
+		t.Fatalf("exp is not ast.HashLiteral. got=%T", stmt.Expression)
+	}
+
+	if len(hash.Pairs) != 3 {
+		t.Errorf("hash.Pairs has wrong length. got=%d", len(hash.Pairs))
+	}
+
+	tests := map[string]func(ast.Expression){
+		"one": func(e ast.Expression) {
+			testInfixExpression(t, e, 0, "+", 1)
+		},
+		"two": func(e ast.Expression) {
+			testInfixExpression(t, e, 10, "-", 8)
+		},
+		"three": func(e ast.Expression) {
+			testInfixExpression(t, e, 15, "/", 5)
+		},
+	}
+
+	for key, value := range hash.Pairs {
+		literal, ok := key.(*ast.StringLiteral)
+		if !ok {
+			t.Errorf("key is not ast.StringLiteral. got=%T", key)
+			continue
+		}
+
+		testFunc, ok := tests[literal.String()]
+		if !ok {
+			t.Errorf("No test function for key %q found", literal.String())
+			continue
+		}
+
+		testFunc(value)
+	}
+}
+
+func TestParserErrorPositions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x 5;", "script.monkey:1:7: expected next token to be =, got INT instead"},
+		{"let x = 1;\nadd(1, 2;", "script.monkey:2:9: expected next token to be ), got ; instead"},
+		{"let x = 1;\n  let y = );", "script.monkey:2:11: no prefix parse function for ) found"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewWithFilename("script.monkey", tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser errors for %q", tt.input)
+			continue
+		}
+		if errors[0].String() != tt.expected {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expected, errors[0].String())
+		}
+	}
+}
+
+func TestNodePositions(t *testing.T) {
+	input := `let add = fn(a, b) { a + b };
+add(1, [2, 3][0]);`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	tests := []struct {
+		node                ast.Node
+		startLine, startCol int
+		endLine, endCol     int
+	}{
+		{program, 1, 1, 2, 18},
+		{program.Statements[0], 1, 1, 1, 29},
+		{program.Statements[0].(*ast.LetStatement).Value, 1, 11, 1, 29},
+		{program.Statements[1], 2, 1, 2, 18},
+		{program.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.CallExpression).Arguments[1], 2, 8, 2, 17},
+	}
+
+	for i, tt := range tests {
+		pos, end := tt.node.Pos(), tt.node.End()
+		if pos.Line != tt.startLine || pos.Column != tt.startCol {
+			t.Errorf("tests[%d] - Pos() wrong. expected=%d:%d, got=%s", i, tt.startLine, tt.startCol, pos)
+		}
+		if end.Line != tt.endLine || end.Column != tt.endCol {
+			t.Errorf("tests[%d] - End() wrong. expected=%d:%d, got=%s", i, tt.endLine, tt.endCol, end)
+		}
+	}
+}
+
+func TestParserDiagnostics(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"let x = (1 + 2;",
+			`1:15: error: expected next token to be ), got ; instead
+  |
+1 | let x = (1 + 2;
+  |               ^
+  = hint: to match ( at 1:9
+`,
+		},
+		{
+			"let add = fn(a, b {\n\ta + b\n};",
+			`1:19: error: expected next token to be ), got { instead
+  |
+1 | let add = fn(a, b {
+  |                   ^
+  = hint: to match ( at 1:13
+`,
+		},
+		{
+			"let x = 1;\n\tlet = 5;",
+			`2:6: error: expected next token to be IDENT, got = instead
+  |
+2 | 	let = 5;
+  | 	    ^
+`,
+		},
+		{
+			"let xs = [1, 2;",
+			`1:15: error: expected next token to be ], got ; instead
+  |
+1 | let xs = [1, 2;
+  |               ^
+  = hint: to match [ at 1:10
+`,
+		},
+		{
+			"let while = 1;",
+			`1:5: error: cannot use keyword "while" as a name
+  |
+1 | let while = 1;
+  |     ^~~~~
+  = hint: "while" is reserved; choose a different name
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser errors for %q", tt.input)
+			continue
+		}
+		if errors[0].Severity != diagnostic.Error {
+			t.Errorf("wrong severity. got=%s", errors[0].Severity)
+		}
+
+		rendered := diagnostic.Render(tt.input, errors[0])
+		if rendered != tt.expected {
+			t.Errorf("wrong rendering. expected=\n%s\ngot=\n%s", tt.expected, rendered)
+		}
+	}
+}
+
+func TestErrorRecovery(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedErrors []string
+		expectedStmts  int
+	}{
+		{
+			"let = 5; let y = 10; y;",
+			[]string{"1:5: expected next token to be IDENT, got = instead"},
+			2,
+		},
+		{
+			"let x = ); let y = ) + (; let z = 1;",
+			[]string{
+				"1:9: no prefix parse function for ) found",
+				"1:20: no prefix parse function for ) found",
+			},
+			1,
+		},
+		{
+			"let a = 1\nlet b = ;\nreturn a;",
+			[]string{"2:9: no prefix parse function for ; found"},
+			2,
+		},
+		{
+			"if (x { 1 } let ok = true;",
+			[]string{"1:7: expected next token to be ), got { instead"},
+			1,
+		},
+		{
+			"let f = fn(x) { let = 1; x }; let g = fn() { return ); }; f(1);",
+			[]string{
+				"1:21: expected next token to be IDENT, got = instead",
+				"1:53: no prefix parse function for ) found",
+			},
+			3,
+		},
+		{
+			"let f = fn(1, y) { return y; }; let g = 2;",
+			[]string{"1:12: expected parameter name to be IDENT, got INT instead"},
+			1,
+		},
+		{
+			"let f = fn(x, true) { x }; f(1)",
+			[]string{`1:15: cannot use keyword "true" as a name`},
+			1,
+		},
+		{
+			"} let x = 1;",
+			[]string{"1:1: no prefix parse function for } found"},
+			1,
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != len(tt.expectedErrors) {
+			t.Errorf("input %q - wrong number of errors. want=%d, got=%d", tt.input, len(tt.expectedErrors), len(errors))
+			for _, e := range errors {
+				t.Errorf("\tparser error: %q", e.String())
+			}
+			continue
+		}
+		for i, expected := range tt.expectedErrors {
+			if errors[i].String() != expected {
+				t.Errorf("input %q - wrong error. expected=%q, got=%q", tt.input, expected, errors[i].String())
+			}
+		}
+
+		if len(program.Statements) != tt.expectedStmts {
+			t.Errorf("input %q - wrong number of statements. want=%d, got=%d (%s)",
+				tt.input, tt.expectedStmts, len(program.Statements), program.String())
+		}
+	}
+}
+
+func TestKeywordsAsNames(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let for = 1;", `1:5: cannot use keyword "for" as a name`},
+		{"let null = 1;", `1:5: cannot use keyword "null" as a name`},
+		{"let fn = 1;", `1:5: cannot use keyword "fn" as a name`},
+		{"fn(x, in) { x }", `1:7: cannot use keyword "in" as a name`},
+		{"fn(import) { 1 }", `1:4: cannot use keyword "import" as a name`},
+		{`let "if" = 1;`, "1:5: expected next token to be IDENT, got STRING instead"}, //字面量与关键字相同的字符串不是关键字
+		{`const "while" = 1;`, "1:7: expected next token to be IDENT, got STRING instead"},
+		{`for ("in" in [1]) { 1 }`, "1:6: expected next token to be IDENT, got STRING instead"},
+		{`fn("fn") { 1 }`, "1:4: expected parameter name to be IDENT, got STRING instead"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) != 1 {
+			t.Errorf("input %q: expected 1 error, got=%d", tt.input, len(errors))
+			continue
+		}
+		if errors[0].String() != tt.expected {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expected, errors[0].String())
+		}
+	}
+}
+
+func TestIdentifiersWithDigits(t *testing.T) {
+	input := "let x1 = 5; let v2beta = x1 * 2; v2beta"
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 3 {
+		t.Fatalf("program.Statements does not contain 3 statements. got=%d", len(program.Statements))
+	}
+	if !testLetStatement(t, program.Statements[0], "x1") || !testLetStatement(t, program.Statements[1], "v2beta") {
+		return
+	}
+	stmt := program.Statements[2].(*ast.ExpressionStatement)
+	testIdentifier(t, stmt.Expression, "v2beta")
+}
+
+func TestCommentsAreIgnored(t *testing.T) {
+	input := `// compute
+let x = 1 + /* inline */ 2; // done`
+
+	for _, l := range []*lexer.Lexer{lexer.New(input), lexer.New(input, lexer.WithComments())} {
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != "let x = (1 + 2);" {
+			t.Errorf("program.String() wrong. got=%q", program.String())
+		}
+	}
+}
+
+func TestLexerErrorsAreReported(t *testing.T) {
+	l := lexer.New("let x = 1;\n/* oops")
+	p := New(l)
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) != 1 {
+		t.Fatalf("wrong number of errors. want=1, got=%d", len(errors))
+	}
+	if errors[0].String() != "2:1: unterminated block comment" {
+		t.Errorf("wrong error. got=%q", errors[0].String())
+	}
+}
+
+func TestIllegalStringIsReportedOnce(t *testing.T) {
+	input := `let s = "a\qb";`
+	p := New(lexer.New(input))
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) != 1 { //不应该再出现no prefix parse function for ILLEGAL found
+		t.Fatalf("wrong number of errors. want=1, got=%v", errors)
+	}
+	expected := `1:11: error: invalid escape sequence \q
+  |
+1 | let s = "a\qb";
+  |           ^~
+  = hint: supported escapes are \n, \t, \", \\ and \u{...}
+`
+	if rendered := diagnostic.Render(input, errors[0]); rendered != expected {
+		t.Errorf("wrong rendered error. expected=\n%s\ngot=\n%s", expected, rendered)
+	}
+}
+
+func TestTrace(t *testing.T) {
+	var out bytes.Buffer
+	p := New(lexer.New("-a * b"), WithTrace(&out))
+	p.ParseProgram()
+	checkParserErrors(t, p)
+
+	expected := `BEGIN parseStatement
+	BEGIN parseExpressionStatement
+		BEGIN parseExpression
+			BEGIN parsePrefixExpression
+				BEGIN parseExpression
+					BEGIN parseIdentifier
+					END parseIdentifier
+				END parseExpression
+			END parsePrefixExpression
+			BEGIN parseInfixExpression
+				BEGIN parseExpression
+					BEGIN parseIdentifier
+					END parseIdentifier
+				END parseExpression
+			END parseInfixExpression
+		END parseExpression
+	END parseExpressionStatement
+END parseStatement
+`
+	if out.String() != expected {
+		t.Errorf("wrong trace. expected=\n%s\ngot=\n%s", expected, out.String())
+	}
+}
+
+func TestTraceIsPerParser(t *testing.T) {
+	input := "let add = fn(a, b) { a + b }; add(1, 2 * 3);"
+
+	var reference bytes.Buffer
+	New(lexer.New(input), WithTrace(&reference)).ParseProgram()
+
+	var wg sync.WaitGroup
+	outputs := make([]bytes.Buffer, 8)
+	for i := range outputs {
+		wg.Add(1)
+		go func(out *bytes.Buffer) {
+			defer wg.Done()
+			New(lexer.New(input), WithTrace(out)).ParseProgram()
+		}(&outputs[i])
+	}
+	wg.Wait()
+
+	for i := range outputs {
+		if outputs[i].String() != reference.String() {
+			t.Errorf("trace of parser %d differs from reference", i)
+		}
+	}
+}
+
+func FuzzParseProgram(f *testing.F) {
+	seeds := []string{
+		"",
+		"let x =",
+		"let x = 5; let y = !",
+		"let f = fn(x, y) { x + y }; f(1, 2 * 3);",
+		"if (x { 1 } else {",
+		`{"a": 1, "b": [1, 2][0]}["a"]`,
+		"fn(1, true) { }",
+		"add(1, 2;",
+		"} ) ] let = ;",
+		"/* unterminated",
+		"match (v) { [a, b] if a > b => a, {\"k\": _} => 1, _ => 0 }",
+		"while (i < 3) { i += 1; if (i == 2) { continue } }",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		p := New(lexer.New(input))
+		program := p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			_ = program.String()
+		}
+		_ = program.Pos()
+		_ = program.End()
+	})
 }
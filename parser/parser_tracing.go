@@ -1,32 +1,48 @@
 package parser
 
 import (
-	"fmt"
+	"io"
 	"strings"
 )
 
-var traceLevel int = 0
-
 const traceIdentPlaceholder string = "\t"
 
-func identLevel() string {
-	return strings.Repeat(traceIdentPlaceholder, traceLevel-1)
+// Option 用于在创建语法分析器时进行配置
+type Option func(*Parser)
+
+// WithTrace 开启语法分析的跟踪，每进入和离开一个解析函数时向w输出一行缩进的BEGIN/END记录。
+// 跟踪状态保存在Parser实例中，多个Parser可以在不同的goroutine中同时工作
+func WithTrace(w io.Writer) Option {
+	return func(p *Parser) {
+		p.traceOut = w
+	}
+}
+
+func (p *Parser) identLevel() string {
+	return strings.Repeat(traceIdentPlaceholder, p.traceLevel-1)
 }
 
-func tracePrint(fs string) {
-	fmt.Printf("%s%s\n", identLevel(), fs)
+func (p *Parser) tracePrint(fs string) {
+	io.WriteString(p.traceOut, p.identLevel()+fs+"\n") //整行一次写入，避免与其他写入者交错
 }
 
-func incIdent() { traceLevel = traceLevel + 1 }
-func decIdent() { traceLevel = traceLevel - 1 }
+func (p *Parser) incIdent() { p.traceLevel = p.traceLevel + 1 }
+func (p *Parser) decIdent() { p.traceLevel = p.traceLevel - 1 }
 
-func trace(msg string) string {
-	incIdent()
-	tracePrint("BEGIN " + msg)
+// trace 与untrace配合使用：defer p.untrace(p.trace("parseExpression"))
+func (p *Parser) trace(msg string) string {
+	if p.traceOut == nil {
+		return msg
+	}
+	p.incIdent()
+	p.tracePrint("BEGIN " + msg)
 	return msg
 }
 
-func untrace(msg string) {
-	tracePrint("END " + msg)
-	decIdent()
+func (p *Parser) untrace(msg string) {
+	if p.traceOut == nil {
+		return
+	}
+	p.tracePrint("END " + msg)
+	p.decIdent()
 }
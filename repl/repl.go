@@ -4,8 +4,10 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"monkey/diagnostic"
 	"monkey/evaluator"
 	"monkey/lexer"
+	"monkey/object"
 	"monkey/parser"
 )
 
@@ -13,6 +15,7 @@ const PROMPT = ">> "
 
 func Start(in io.Reader, out io.Writer) {
 	scanner := bufio.NewScanner(in)
+	env := object.NewEnvironment() //环境在多次输入之间共享，使得let绑定可以跨行使用
 
 	for {
 		fmt.Fprint(out, PROMPT)
@@ -26,11 +29,11 @@ func Start(in io.Reader, out io.Writer) {
 		program := p.ParseProgram()
 
 		if len(p.Errors()) != 0 {
-			printParseErrors(out, p.Errors())
+			printParseErrors(out, line, p.Errors())
 			continue
 		}
 
-		evaluated := evaluator.Eval(program)
+		evaluated := evaluator.Eval(program, env)
 		if evaluated != nil {
 			io.WriteString(out, evaluated.Inspect())
 			io.WriteString(out, "\n")
@@ -39,14 +42,11 @@ func Start(in io.Reader, out io.Writer) {
 	}
 }
 
-func printParseErrors(out io.Writer, errors []string) {
+func printParseErrors(out io.Writer, source string, errors []diagnostic.Diagnostic) {
 	io.WriteString(out, MONKEY_FACE)
 	io.WriteString(out, "Woops!something goes wrong.")
 	io.WriteString(out, " parse errors: \n")
-	for _, msg := range errors {
-
-		io.WriteString(out, "\t"+msg+"\t")
-	}
+	io.WriteString(out, diagnostic.RenderAll(source, errors))
 }
 
 const MONKEY_FACE = `            __,__
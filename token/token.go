@@ -1,43 +1,101 @@
 package token
 
+import "fmt"
+
 type TokenType string
 
 //定义词法单元
 type Token struct {
 	Type    TokenType
 	Literal string
+	Pos     Position //词法单元第一个字符的位置
+	End     Position //词法单元最后一个字符之后的位置
+}
+
+// Position 描述源代码中的一个位置，Line和Column均从1开始，Offset为从0开始的字节偏移
+type Position struct {
+	Filename string
+	Offset   int
+	Line     int
+	Column   int
+}
+
+// IsValid 判断位置信息是否有效，零值表示未知的位置
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// String 返回file:line:col形式的位置，没有文件名时省略文件名部分
+func (p Position) String() string {
+	if !p.IsValid() {
+		if p.Filename != "" {
+			return p.Filename
+		}
+		return "-"
+	}
+	if p.Filename == "" {
+		return fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.Filename, p.Line, p.Column)
 }
 
 const (
 	ILLEGAL = "ILLEGAL"
 	EOF     = "EOF"
+	COMMENT = "COMMENT" // 只有在词法分析器开启了保留注释的选项时才会产生
 
 	// Identifiers + literals
-	IDENT = "IDENT" // add, foobar, x, y, ...
-	INT   = "INT"   // 1343456
+	IDENT  = "IDENT"  // add, foobar, x, y, ...
+	INT    = "INT"    // 1343456, 0xff, 0o17, 0b1010, 1_000_000
+	FLOAT  = "FLOAT"  // 3.14, 1e-9, 2.5E+3
+	STRING = "STRING" // "foo bar"
 
 	// Operators
-	ASSIGN   = "="
+	ASSIGN          = "="
+	PLUS_ASSIGN     = "+="
+	MINUS_ASSIGN    = "-="
+	ASTERISK_ASSIGN = "*="
+	SLASH_ASSIGN    = "/="
+
 	PLUS     = "+"
 	MINUS    = "-"
 	BANG     = "!"
 	ASTERISK = "*"
 	SLASH    = "/"
+	PERCENT  = "%"
 
-	LT = "<"
-	GT = ">"
+	LT    = "<"
+	GT    = ">"
+	LT_EQ = "<="
+	GT_EQ = ">="
 
 	EQ     = "=="
 	NOT_EQ = "!="
 
+	AND = "&&"
+	OR  = "||"
+
+	BIT_AND = "&"
+	BIT_OR  = "|"
+	BIT_XOR = "^"
+	SHL     = "<<"
+	SHR     = ">>"
+	TILDE   = "~"
+
+	QUESTION = "?"
+	ARROW    = "=>"
+
 	// Delimiters
 	COMMA     = ","
 	SEMICOLON = ";"
+	COLON     = ":"
 
-	LPAREN = "("
-	RPAREN = ")"
-	LBRACE = "{"
-	RBRACE = "}"
+	LPAREN   = "("
+	RPAREN   = ")"
+	LBRACE   = "{"
+	RBRACE   = "}"
+	LBRACKET = "["
+	RBRACKET = "]"
 
 	// Keywords
 	FUNCTION = "FUNCTION"
@@ -47,16 +105,35 @@ const (
 	IF       = "IF"
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
+	WHILE    = "WHILE"
+	FOR      = "FOR"
+	BREAK    = "BREAK"
+	CONTINUE = "CONTINUE"
+	NULL     = "NULL"
+	IN       = "IN"
+	IMPORT   = "IMPORT"
+	CONST    = "CONST"
+	MATCH    = "MATCH"
 )
 
+// keywords 中的一部分关键字暂时还没有对应的语法，预先保留是为了避免以后的语法与用户的标识符冲突
 var keywords = map[string]TokenType{
-	"fn":     FUNCTION,
-	"let":    LET,
-	"if":     IF,
-	"return": RETURN,
-	"else":   ELSE,
-	"true":   TRUE,
-	"false":  FALSE,
+	"fn":       FUNCTION,
+	"let":      LET,
+	"if":       IF,
+	"return":   RETURN,
+	"else":     ELSE,
+	"true":     TRUE,
+	"false":    FALSE,
+	"while":    WHILE,
+	"for":      FOR,
+	"break":    BREAK,
+	"continue": CONTINUE,
+	"null":     NULL,
+	"in":       IN,
+	"import":   IMPORT,
+	"const":    CONST,
+	"match":    MATCH,
 }
 
 func LookupIdent(ident string) TokenType {
@@ -65,3 +142,8 @@ func LookupIdent(ident string) TokenType {
 	}
 	return IDENT
 }
+
+// IsKeyword 判断tok是否是关键字词法单元。只检查字面量是不够的，例如字符串"if"的字面量同样是if
+func IsKeyword(tok Token) bool {
+	return tok.Type != IDENT && LookupIdent(tok.Literal) == tok.Type
+}